@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// productBundle is a named group of products that can be typed instead of the products themselves.
+type productBundle []bundleMember
+
+// bundleMember is a product in a bundle, optionally limited to the releases between From and Until (both included).
+// In a bundle file, a member can be written as just the product name when it applies to every release.
+type bundleMember struct {
+	Product string `json:"product"`
+	From    string `json:"from,omitempty"`
+	Until   string `json:"until,omitempty"`
+}
+
+func (m *bundleMember) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		m.Product = name
+		return nil
+	}
+
+	type plainMember bundleMember // Avoids calling this method again.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*plainMember)(m))
+}
+
+// appliesTo reports whether the member is part of its bundle for the given release.
+func (m bundleMember) appliesTo(release string) bool {
+	if m.From != "" && releaseIndex(release) < releaseIndex(m.From) {
+		return false
+	}
+	if m.Until != "" && releaseIndex(release) > releaseIndex(m.Until) {
+		return false
+	}
+	return true
+}
+
+// validateBundles checks bundle names and member releases. Member products aren't checked here; they go through
+// resolveProducts like anything else that's typed in, so a misspelled member is reported along with a suggestion.
+func validateBundles(bundles map[string]productBundle) error {
+	var errs []error
+	for name, bundle := range bundles {
+		if !productNamePattern.MatchString(name) {
+			errs = append(errs, fmt.Errorf("bundle name %q is invalid", name))
+		}
+		if strings.EqualFold(name, "all") || strings.EqualFold(name, "pick") || catalogHasProduct(name) {
+			errs = append(errs, fmt.Errorf("bundle %s has the same name as a product or keyword", name))
+		}
+		if len(bundle) == 0 {
+			errs = append(errs, fmt.Errorf("bundle %s has no products", name))
+		}
+		for _, m := range bundle {
+			if m.Product == "" {
+				errs = append(errs, fmt.Errorf("bundle %s has a member with no product", name))
+			}
+			for _, r := range []string{m.From, m.Until} {
+				if _, known := releaseIndexMap[r]; r != "" && !known {
+					errs = append(errs, fmt.Errorf("bundle %s refers to unknown release %q", name, r))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func catalogHasProduct(name string) bool {
+	for _, p := range catalog.Products {
+		if strings.EqualFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultBundlesPath is where bundles are read from when --bundles isn't given.
+func defaultBundlesPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "mpm-go", "bundles.json")
+}
+
+// loadBundles combines the built-in bundles with the ones in the user's bundle file. A user's bundle replaces a
+// built-in one with the same name. The default bundle file is optional, but one given explicitly must exist.
+func (s *mpmSession) loadBundles(path string) error {
+	s.bundles = make(map[string]productBundle, len(catalog.Bundles))
+	for name, bundle := range catalog.Bundles {
+		s.bundles[strings.ToLower(name)] = bundle
+	}
+
+	explicit := path != ""
+	if !explicit {
+		path = defaultBundlesPath()
+		if path == "" {
+			return nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error reading bundles: %w", err)
+	}
+
+	var userBundles map[string]productBundle
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&userBundles); err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if err := validateBundles(userBundles); err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	for name, bundle := range userBundles {
+		s.bundles[strings.ToLower(name)] = bundle
+	}
+	return nil
+}
+
+// expandBundles replaces every bundle name in the input with the bundle's products for the selected release.
+// Anything that isn't a bundle is left alone. A bundle with no products for the release is unresolved, rather than
+// quietly selecting nothing.
+func (s *mpmSession) expandBundles(inputProducts []string) (expanded []string, unresolved []productSuggestion) {
+	for _, input := range inputProducts {
+		bundle, ok := s.bundles[strings.ToLower(input)]
+		if !ok {
+			expanded = append(expanded, input)
+			continue
+		}
+		found := false
+		for _, m := range bundle {
+			if m.appliesTo(s.release) {
+				expanded = append(expanded, m.Product)
+				found = true
+			}
+		}
+		if !found {
+			unresolved = append(unresolved, productSuggestion{input: input, problem: fmt.Sprintf("bundle %s has no products for %s", input, s.release)})
+		}
+	}
+	return expanded, unresolved
+}
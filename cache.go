@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// mpmCacheDir is where downloaded copies of MPM are kept between runs, with a folder for each platform so that the
+// Intel and ARM versions for Macs don't replace each other.
+func mpmCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "mpm-go", "mpm"), nil
+}
+
+// mpmCacheSlot is the cache folder for the selected platform.
+func (s *mpmSession) mpmCacheSlot() (string, error) {
+	cacheDir, err := mpmCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, s.platform), nil
+}
+
+// defaultMPMDownloadPath is the cache folder for the selected platform, or the temporary directory if there's no
+// user cache directory.
+func (s *mpmSession) defaultMPMDownloadPath() (path string, cached bool) {
+	slot, err := s.mpmCacheSlot()
+	if err != nil {
+		return s.defaultTMP, false
+	}
+	return slot, true
+}
+
+// cachedMPM is the copy of MPM in one of the cache's folders.
+type cachedMPM struct {
+	platform string
+	path     string
+	info     os.FileInfo
+	record   *downloadRecord // nil if it wasn't downloaded by this program.
+}
+
+// cachedMPMs lists every copy of MPM in the cache, sorted by platform.
+func cachedMPMs() ([]cachedMPM, error) {
+	cacheDir, err := mpmCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	var found []cachedMPM
+	for _, platform := range platformNames() {
+		path := filepath.Join(cacheDir, platform, (&mpmSession{platform: platform}).mpmBinaryName())
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		found = append(found, cachedMPM{platform: platform, path: path, info: info, record: readDownloadRecord(path)})
+	}
+	return found, nil
+}
+
+// check reports what's wrong with a cached copy of MPM, if anything.
+func (c cachedMPM) check() error {
+	platforms, description, err := executablePlatforms(c.path)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(platforms, c.platform) {
+		return fmt.Errorf("it is %s, not MPM for %s", description, c.platform)
+	}
+	if c.record != nil {
+		checksum, err := fileSHA256(c.path)
+		if err != nil {
+			return err
+		}
+		if checksum != c.record.SHA256 {
+			return errors.New("it has changed since it was downloaded")
+		}
+	}
+	return nil
+}
+
+// Look after the copies of MPM kept in the cache.
+func runCacheCommand(args []string, w io.Writer) error {
+	const usage = "Use one of: cache list, cache verify, cache clean [--platform platform]"
+	if len(args) == 0 {
+		return errors.New("no cache command was given. " + usage)
+	}
+
+	switch args[0] {
+	case "list":
+		cacheDir, err := mpmCacheDir()
+		if err != nil {
+			return err
+		}
+		cached, err := cachedMPMs()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "MPM cache: %s\n", cacheDir)
+		if len(cached) == 0 {
+			fmt.Fprintln(w, "The cache is empty.")
+			return nil
+		}
+		for _, c := range cached {
+			fmt.Fprintf(w, "\n%s\n  %s\n  %s, downloaded %s\n", c.platform, c.path, formatBytes(c.info.Size()), c.info.ModTime().Format(time.DateTime))
+			if c.record != nil {
+				fmt.Fprintf(w, "  From %s\n  SHA-256 %s\n", c.record.URL, c.record.SHA256)
+			}
+		}
+		return nil
+
+	case "verify":
+		cached, err := cachedMPMs()
+		if err != nil {
+			return err
+		}
+		if len(cached) == 0 {
+			fmt.Fprintln(w, "The cache is empty.")
+			return nil
+		}
+		var errs []error
+		for _, c := range cached {
+			if err := c.check(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w. Run \"cache clean --platform %s\" to remove it", c.platform, err, c.platform))
+				continue
+			}
+			fmt.Fprintf(w, "%s: OK\n", c.platform)
+		}
+		return errors.Join(errs...)
+
+	case "clean":
+		fs := flag.NewFlagSet("cache clean", flag.ContinueOnError)
+		platformFlag := fs.String("platform", "", "Only remove MPM for this platform: "+strings.Join(platformNames(), ", ")+".")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		cacheDir, err := mpmCacheDir()
+		if err != nil {
+			return err
+		}
+		target := cacheDir
+		if *platformFlag != "" {
+			platform, ok := catalog.matchPlatform(*platformFlag)
+			if !ok {
+				return fmt.Errorf("unknown platform %q. Use one of: %s", *platformFlag, strings.Join(platformNames(), ", "))
+			}
+			target = filepath.Join(cacheDir, platform)
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Removed %s\n", target)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown cache command %q. %s", args[0], usage)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The product catalog lists every release and product this program knows about.
+// Supporting a new release or product only requires editing catalog.json.
+//
+//go:embed catalog.json
+var catalogJSON []byte
+
+// catalogSchemaVersion is the layout of catalog.json this program understands.
+const catalogSchemaVersion = 1
+
+var (
+	releasePattern     = regexp.MustCompile(`^R\d{4}[ab]$`)
+	productNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+)
+
+type productCatalog struct {
+	SchemaVersion  int                        `json:"schemaVersion"`
+	Releases       []string                   `json:"releases"` // Oldest to newest.
+	DefaultRelease string                     `json:"defaultRelease"`
+	Platforms      map[string]catalogPlatform `json:"platforms"`
+	Products       []catalogProduct           `json:"products"`
+	Bundles        map[string]productBundle   `json:"bundles"` // Built in, users can add their own.
+}
+
+type catalogPlatform struct {
+	FirstRelease string `json:"firstRelease"`
+}
+
+// catalogProduct is available from Introduced up to, but not including, Removed. A platform can override either one.
+type catalogProduct struct {
+	Name              string                     `json:"name"`
+	Introduced        string                     `json:"introduced"`
+	Removed           string                     `json:"removed,omitempty"`
+	Platforms         []string                   `json:"platforms"`
+	Requires          []string                   `json:"requires,omitempty"` // Products that must be installed with this one.
+	Replaces          []string                   `json:"replaces,omitempty"` // Older names of this product.
+	PlatformOverrides map[string]productOverride `json:"platformOverrides,omitempty"`
+}
+
+type productOverride struct {
+	Introduced string `json:"introduced,omitempty"`
+	Removed    string `json:"removed,omitempty"`
+}
+
+// The embedded catalog ships with the program, so a broken one is a bug rather than something the user can fix.
+var catalog = func() *productCatalog {
+	c, err := parseCatalog(catalogJSON)
+	if err != nil {
+		panic("invalid product catalog: " + err.Error())
+	}
+	return c
+}()
+
+func parseCatalog(data []byte) (*productCatalog, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	c := &productCatalog{}
+	if err := decoder.Decode(c); err != nil {
+		return nil, err
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// validate checks the catalog against its schema and reports every problem found.
+func (c *productCatalog) validate() error {
+	if c.SchemaVersion != catalogSchemaVersion {
+		return fmt.Errorf("unsupported schema version %d, expected %d", c.SchemaVersion, catalogSchemaVersion)
+	}
+
+	var errs []error
+	known := make(map[string]bool, len(c.Releases))
+	if len(c.Releases) == 0 {
+		errs = append(errs, errors.New("no releases are listed"))
+	}
+	for _, r := range c.Releases {
+		if !releasePattern.MatchString(r) {
+			errs = append(errs, fmt.Errorf("release %q is not formatted like R2025b", r))
+		}
+		if known[r] {
+			errs = append(errs, fmt.Errorf("release %s is listed more than once", r))
+		}
+		known[r] = true
+	}
+	checkRelease := func(what, r string) {
+		if !known[r] {
+			errs = append(errs, fmt.Errorf("%s refers to unknown release %q", what, r))
+		}
+	}
+
+	checkRelease("defaultRelease", c.DefaultRelease)
+	if len(c.Platforms) == 0 {
+		errs = append(errs, errors.New("no platforms are listed"))
+	}
+	for name, p := range c.Platforms {
+		checkRelease("platform "+name, p.FirstRelease)
+	}
+
+	seen := make(map[string]bool, len(c.Products))
+	for _, p := range c.Products {
+		if !productNamePattern.MatchString(p.Name) {
+			errs = append(errs, fmt.Errorf("product name %q is invalid", p.Name))
+		}
+		if seen[strings.ToLower(p.Name)] {
+			errs = append(errs, fmt.Errorf("product %s is listed more than once", p.Name))
+		}
+		seen[strings.ToLower(p.Name)] = true
+
+		checkRelease("product "+p.Name, p.Introduced)
+		if p.Removed != "" {
+			checkRelease("product "+p.Name, p.Removed)
+			if known[p.Introduced] && known[p.Removed] && slices.Index(c.Releases, p.Removed) <= slices.Index(c.Releases, p.Introduced) {
+				errs = append(errs, fmt.Errorf("product %s is removed before it is introduced", p.Name))
+			}
+		}
+
+		if len(p.Platforms) == 0 {
+			errs = append(errs, fmt.Errorf("product %s has no platforms", p.Name))
+		}
+		for _, platform := range p.Platforms {
+			if _, ok := c.Platforms[platform]; !ok {
+				errs = append(errs, fmt.Errorf("product %s refers to unknown platform %q", p.Name, platform))
+			}
+		}
+		for platform, o := range p.PlatformOverrides {
+			if !slices.Contains(p.Platforms, platform) {
+				errs = append(errs, fmt.Errorf("product %s overrides platform %q, which it isn't available on", p.Name, platform))
+			}
+			if o.Introduced != "" {
+				checkRelease("product "+p.Name, o.Introduced)
+			}
+			if o.Removed != "" {
+				checkRelease("product "+p.Name, o.Removed)
+			}
+		}
+	}
+
+	// Only check requirements, renames and bundles once every product name is known.
+	for _, p := range c.Products {
+		for _, required := range p.Requires {
+			if c.product(required) == nil || required == p.Name {
+				errs = append(errs, fmt.Errorf("product %s requires unknown product %q", p.Name, required))
+			}
+		}
+		for _, old := range p.Replaces {
+			if c.product(old) == nil {
+				errs = append(errs, fmt.Errorf("product %s replaces unknown product %q", p.Name, old))
+			}
+		}
+	}
+	for name, bundle := range c.Bundles {
+		if !productNamePattern.MatchString(name) || seen[strings.ToLower(name)] {
+			errs = append(errs, fmt.Errorf("bundle name %q is invalid or used by a product", name))
+		}
+		for _, m := range bundle {
+			if c.product(m.Product) == nil {
+				errs = append(errs, fmt.Errorf("bundle %s refers to unknown product %q", name, m.Product))
+			}
+			if m.From != "" {
+				checkRelease("bundle "+name, m.From)
+			}
+			if m.Until != "" {
+				checkRelease("bundle "+name, m.Until)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// releasesFor lists the releases MPM can install on a platform, oldest first.
+func (c *productCatalog) releasesFor(platform string) []string {
+	p, ok := c.Platforms[platform]
+	if !ok {
+		return nil
+	}
+	return c.Releases[slices.Index(c.Releases, p.FirstRelease):]
+}
+
+// releaseRange gives the release the product was introduced in on a platform and, if it has been, the release it was removed in.
+func (p *catalogProduct) releaseRange(platform string) (introduced, removed string) {
+	introduced, removed = p.Introduced, p.Removed
+	if o, ok := p.PlatformOverrides[platform]; ok {
+		if o.Introduced != "" {
+			introduced = o.Introduced
+		}
+		if o.Removed != "" {
+			removed = o.Removed
+		}
+	}
+	return introduced, removed
+}
+
+// availableOn reports whether the product can be installed for the given platform and release.
+func (p *catalogProduct) availableOn(platform, release string) bool {
+	if !slices.Contains(p.Platforms, platform) {
+		return false
+	}
+	introduced, removed := p.releaseRange(platform)
+
+	selectedIdx := releaseIndex(release)
+	if selectedIdx < releaseIndex(introduced) {
+		return false
+	}
+	return removed == "" || selectedIdx < releaseIndex(removed)
+}
+
+// product looks up a product by its exact name.
+func (c *productCatalog) product(name string) *catalogProduct {
+	for i := range c.Products {
+		if c.Products[i].Name == name {
+			return &c.Products[i]
+		}
+	}
+	return nil
+}
+
+// matchPlatform finds a platform in the catalog, ignoring case, and returns its canonical spelling.
+func (c *productCatalog) matchPlatform(platform string) (string, bool) {
+	for name := range c.Platforms {
+		if strings.EqualFold(name, platform) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// productsFor lists every product available for a platform and release, sorted by name.
+func (c *productCatalog) productsFor(platform, release string) []string {
+	var products []string
+	for i := range c.Products {
+		if c.Products[i].availableOn(platform, release) {
+			products = append(products, c.Products[i].Name)
+		}
+	}
+	slices.SortFunc(products, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+	return products
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	readline "github.com/Jestzer/readlineJestzer"
+)
+
+// promptCompleter lets each prompt choose what Tab completes, since there's only one readline instance.
+type promptCompleter struct {
+	current readline.AutoCompleter
+}
+
+func (p *promptCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	if p.current == nil {
+		return nil, 0
+	}
+	return p.current.Do(line, pos)
+}
+
+// List and auto-complete files and folders with tabbing.
+var fileCompleter = readline.NewPrefixCompleter(
+	readline.PcItemDynamic(listFiles),
+)
+
+// wordCompleter completes the word under the cursor from a list, ignoring case like resolveProducts does.
+// A leading "-" is skipped so that excluded products complete too.
+type wordCompleter func() []string
+
+func (w wordCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	typed := string(line[:pos])
+	word := typed[strings.LastIndexAny(typed, " \t")+1:]
+	word = strings.TrimPrefix(word, "-")
+	wordLower := strings.ToLower(word)
+
+	var candidates [][]rune
+	for _, candidate := range w() {
+		if strings.HasPrefix(strings.ToLower(candidate), wordLower) {
+			candidates = append(candidates, []rune(candidate[len(word):]))
+		}
+	}
+
+	// Move on to the next word once there's nothing left to choose between.
+	if len(candidates) == 1 {
+		candidates[0] = append(candidates[0], ' ')
+	}
+	return candidates, len([]rune(word))
+}
+
+func (s *mpmSession) completeFiles() {
+	s.completer.current = fileCompleter
+}
+
+func (s *mpmSession) completeWords(words func() []string) {
+	s.completer.current = wordCompleter(words)
+}
+
+// productCompletions is everything that can be typed at the product prompt for the selected release and platform.
+func (s *mpmSession) productCompletions() []string {
+	var bundles []string
+	for name := range s.bundles {
+		bundles = append(bundles, name)
+	}
+	slices.Sort(bundles)
+
+	words := append([]string{"all", "pick"}, bundles...)
+	return append(words, catalog.productsFor(s.platform, s.release)...)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// installSpec is the answer file given with --config. It describes an entire installation so nothing needs to be asked.
+type installSpec struct {
+	Release     string   `json:"release"`
+	Products    []string `json:"products"`
+	Bundle      string   `json:"bundle"`
+	BundlesFile string   `json:"bundlesFile"`
+	Exclude     []string `json:"exclude"`
+	Destination string   `json:"destination"`
+	MPMDir      string   `json:"mpmDir"`
+	License     string   `json:"license"`
+	Arch        string   `json:"arch"`
+	MPMSHA256   string   `json:"mpmSHA256"`
+	MPMManifest string   `json:"mpmManifest"`
+	MPMSource   string   `json:"mpmSource"`
+	Proxy       string   `json:"proxy"`
+	NoProxy     string   `json:"noProxy"`
+	CABundle    string   `json:"caBundle"`
+
+	ForceDownload bool `json:"forceDownload"`
+	DryRun        bool `json:"dryRun"`
+
+	ImportInputFile string `json:"importInputFile"`
+	ExportInputFile string `json:"exportInputFile"`
+}
+
+func loadInstallSpec(path string) (*installSpec, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Catch misspelled keys instead of silently ignoring them.
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+
+	spec := &installSpec{}
+	if err := decoder.Decode(spec); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// mergeInto fills in any option that wasn't already given on the command line, so flags always win over the file.
+func (spec *installSpec) mergeInto(opts *cliOptions) {
+	setIfEmpty := func(dest *string, value string) {
+		if *dest == "" {
+			*dest = os.ExpandEnv(value)
+		}
+	}
+	setIfEmpty(&opts.release, spec.Release)
+	setIfEmpty(&opts.products, strings.TrimSpace(spec.Bundle+" "+strings.Join(spec.Products, " ")))
+	setIfEmpty(&opts.bundlesPath, spec.BundlesFile)
+	opts.exclude = append(opts.exclude, spec.Exclude...)
+	setIfEmpty(&opts.destination, spec.Destination)
+	setIfEmpty(&opts.mpmDir, spec.MPMDir)
+	setIfEmpty(&opts.license, spec.License)
+	setIfEmpty(&opts.arch, spec.Arch)
+	setIfEmpty(&opts.mpmSHA256, spec.MPMSHA256)
+	setIfEmpty(&opts.mpmManifest, spec.MPMManifest)
+	setIfEmpty(&opts.mpmSource, spec.MPMSource)
+	setIfEmpty(&opts.proxy, spec.Proxy)
+	setIfEmpty(&opts.noProxy, spec.NoProxy)
+	setIfEmpty(&opts.caBundle, spec.CABundle)
+	opts.forceDownload = opts.forceDownload || spec.ForceDownload
+	opts.dryRun = opts.dryRun || spec.DryRun
+	setIfEmpty(&opts.importInputFile, spec.ImportInputFile)
+	setIfEmpty(&opts.exportInputFile, spec.ExportInputFile)
+}
+
+// validateOptions runs the same checks as the prompts on everything given up front and reports every problem at once,
+// before anything is downloaded or installed.
+func (s *mpmSession) validateOptions() error {
+	// How to get MPM is only ever given as options, even with prompts, so it's checked before anything is downloaded.
+	var errs []error
+	if err := s.network.check(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := s.mpmSourceLocation(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := s.expectedMPMChecksum(); err != nil {
+		errs = append(errs, err)
+	}
+	if !s.nonInteractive {
+		return errors.Join(errs...)
+	}
+
+	if s.release == "" {
+		errs = append(errs, errors.New("no release was given"))
+	}
+	if len(s.products) == 0 {
+		errs = append(errs, errors.New("no products were given. Use \"all\" to install every product"))
+	}
+	if s.installPath == "" {
+		errs = append(errs, errors.New("no destination was given"))
+	}
+
+	if s.release != "" {
+		s.setValidReleases()
+		if release, ok := s.matchRelease(s.release); ok {
+			s.release = release
+			if len(s.products) > 0 {
+				products, _, unresolved, _ := s.productsFromInput(s.products, catalog.productsFor(s.platform, s.release))
+				for _, u := range unresolved {
+					errs = append(errs, errors.New(u.message()))
+				}
+				_, _, conflicts := catalog.resolveDependencies(s.platform, s.release, products)
+				if err := s.dependencyConflictsError(conflicts); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		} else {
+			errs = append(errs, fmt.Errorf("invalid release %q. %s", s.release, s.releaseRangeHint()))
+		}
+	}
+
+	if s.licenseUsed {
+		if err := checkLicenseFile(s.licensePath); err != nil {
+			errs = append(errs, fmt.Errorf("license file: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
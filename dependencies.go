@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// addedDependency is a product that wasn't asked for, but is needed by one that was.
+type addedDependency struct {
+	product    string
+	requiredBy string
+}
+
+// dependencyConflict is a required product that can't be installed with the selected platform and release.
+type dependencyConflict struct {
+	product  string
+	requires string
+}
+
+// availableName finds the name a product goes by for a platform and release. A required product may have been
+// renamed since the requirement was written, so its older and newer names are tried too.
+func (c *productCatalog) availableName(name, platform, release string) (string, bool) {
+	p := c.product(name)
+	if p == nil {
+		return "", false
+	}
+	if p.availableOn(platform, release) {
+		return name, true
+	}
+	for _, old := range p.Replaces {
+		if c.product(old).availableOn(platform, release) {
+			return old, true
+		}
+	}
+	for i := range c.Products {
+		if slices.Contains(c.Products[i].Replaces, name) && c.Products[i].availableOn(platform, release) {
+			return c.Products[i].Name, true
+		}
+	}
+	return "", false
+}
+
+// explainUnavailable gives the reason for products that were asked for but are in the catalog, just not for the
+// selected platform and release, instead of treating them as typos. If the product goes by another name for the
+// release, that name is suggested instead.
+func (s *mpmSession) explainUnavailable(unresolved []productSuggestion) {
+	for i, u := range unresolved {
+		j := slices.IndexFunc(catalog.Products, func(p catalogProduct) bool { return strings.EqualFold(p.Name, u.input) })
+		if j < 0 {
+			continue
+		}
+		name := catalog.Products[j].Name
+		unresolved[i].problem = fmt.Sprintf("%s isn't available for %s on %s", name, s.release, s.platform)
+		unresolved[i].suggestion, _ = catalog.availableName(name, s.platform, s.release)
+	}
+}
+
+// resolveDependencies adds every product the given products require, directly or not, that isn't already listed.
+func (c *productCatalog) resolveDependencies(platform, release string, products []string) ([]string, []addedDependency, []dependencyConflict) {
+	resolved := slices.Clone(products)
+	var added []addedDependency
+	var conflicts []dependencyConflict
+
+	// Products added along the way are appended, so they get their own requirements checked too.
+	for i := 0; i < len(resolved); i++ {
+		p := c.product(resolved[i])
+		if p == nil {
+			continue
+		}
+		for _, required := range p.Requires {
+			name, ok := c.availableName(required, platform, release)
+			if !ok {
+				conflicts = append(conflicts, dependencyConflict{product: p.Name, requires: required})
+				continue
+			}
+			if !slices.Contains(resolved, name) {
+				resolved = append(resolved, name)
+				added = append(added, addedDependency{product: name, requiredBy: p.Name})
+			}
+		}
+	}
+	return resolved, added, conflicts
+}
+
+// dependencyConflictsError describes every conflict at once, or returns nil if there are none.
+func (s *mpmSession) dependencyConflictsError(conflicts []dependencyConflict) error {
+	var errs []error
+	for _, c := range conflicts {
+		errs = append(errs, fmt.Errorf("%s requires %s, which isn't available for %s on %s", c.product, c.requires, s.release, s.platform))
+	}
+	return errors.Join(errs...)
+}
+
+// withDependencies adds the products required by the selected ones and tells the user what was added and why.
+func (s *mpmSession) withDependencies(products []string) ([]string, error) {
+	resolved, added, conflicts := catalog.resolveDependencies(s.platform, s.release, products)
+	if err := s.dependencyConflictsError(conflicts); err != nil {
+		return nil, err
+	}
+	for _, a := range added {
+		fmt.Printf("Adding %s, which is required by %s.\n", s.greenText(a.product), a.requiredBy)
+	}
+	return resolved, nil
+}
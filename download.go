@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// downloader fetches a file over HTTP. Interrupted downloads are kept next to the destination with a ".part" suffix
+// and picked up where they left off, both on a retry and the next time the program is run. The part has a record of
+// its own, so that it's only continued if the server still has the same file.
+//
+// Each finished download is described by a record saved next to it. Later downloads of the same URL ask the server
+// whether the file has changed since, and keep the existing file if it hasn't.
+type downloader struct {
+	client   *http.Client
+	retries  int           // Attempts made after the first one fails.
+	backoff  time.Duration // Wait before the first retry. It doubles with each retry after that.
+	progress io.Writer     // Where the progress bar is drawn, or nil for none.
+	force    bool          // Download again even if the existing file is current.
+}
+
+// downloadRecord is saved next to a finished download, so that the next download can ask the server for the file
+// only if it's changed. The checksum makes sure the file on disk is still the one that was downloaded.
+type downloadRecord struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	SHA256       string `json:"sha256"`
+}
+
+var errNotModified = errors.New("not modified")
+
+func downloadRecordPath(filePath string) string {
+	return filePath + ".download.json"
+}
+
+// readDownloadRecord returns the record for filePath, or nil if there isn't a usable one.
+func readDownloadRecord(filePath string) *downloadRecord {
+	data, err := os.ReadFile(downloadRecordPath(filePath))
+	if err != nil {
+		return nil
+	}
+	record := &downloadRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil
+	}
+	return record
+}
+
+func writeDownloadRecord(filePath string, record *downloadRecord) error {
+	data, err := json.MarshalIndent(record, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(downloadRecordPath(filePath), data, 0644)
+}
+
+// describes reports whether the record is for a download of url that's still sitting unchanged at filePath, and
+// has something the server can compare against.
+func (r *downloadRecord) describes(url, filePath string) bool {
+	if r == nil || r.URL != url || (r.ETag == "" && r.LastModified == "") {
+		return false
+	}
+	checksum, err := fileSHA256(filePath)
+	return err == nil && checksum == r.SHA256
+}
+
+// resumeValidator is what an If-Range header can use to make sure a partial download of url is still of the file on
+// the server: a strong ETag, or else the Last-Modified date. It's empty if the record has neither.
+func (r *downloadRecord) resumeValidator(url string) string {
+	switch {
+	case r == nil || r.URL != url:
+		return ""
+	case r.ETag != "" && !strings.HasPrefix(r.ETag, "W/"):
+		return r.ETag
+	default:
+		return r.LastModified
+	}
+}
+
+func newDownloader(progress io.Writer) *downloader {
+	return &downloader{
+		client:   &http.Client{},
+		retries:  4,
+		backoff:  time.Second,
+		progress: progress,
+	}
+}
+
+// httpStatusError is a response that wasn't the file.
+type httpStatusError struct {
+	code   int
+	status string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("download failed: HTTP %s", e.status)
+}
+
+// retryable reports whether trying again might get a different answer.
+func (e *httpStatusError) retryable() bool {
+	return e.code >= 500 || e.code == http.StatusTooManyRequests || e.code == http.StatusRequestTimeout
+}
+
+// download saves url to filePath. The file only appears at filePath once it's complete, so a dropped connection
+// never leaves a half-written copy behind. It returns false if the file already at filePath was current and kept.
+func (d *downloader) download(url, filePath string) (bool, error) {
+	partPath := filePath + ".part"
+
+	// Only ask whether the file changed when starting afresh. A partial download is always finished.
+	var existing *downloadRecord
+	if _, err := os.Stat(partPath); !d.force && os.IsNotExist(err) {
+		if record := readDownloadRecord(filePath); record.describes(url, filePath) {
+			existing = record
+		}
+	}
+
+	var err error
+	for attempt := 0; attempt <= d.retries; attempt++ {
+		if attempt > 0 {
+			wait := d.backoff << (attempt - 1)
+			fmt.Fprintf(os.Stderr, "Download failed (%v). Retrying in %v.\n", err, wait)
+			time.Sleep(wait)
+		}
+
+		var record *downloadRecord
+		record, err = d.attempt(url, partPath, existing)
+		if err == errNotModified {
+			os.Remove(partPath)
+			os.Remove(downloadRecordPath(partPath))
+			return false, nil
+		}
+		if err == nil {
+			if err := os.Rename(partPath, filePath); err != nil {
+				return false, err
+			}
+			os.Remove(downloadRecordPath(partPath))
+			// Without a record, the file is simply downloaded again next time, so failing to save one isn't an error.
+			if record.SHA256, err = fileSHA256(filePath); err == nil {
+				writeDownloadRecord(filePath, record)
+			}
+			return true, nil
+		}
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			return false, err
+		}
+	}
+	return false, err
+}
+
+// attempt makes one request, continuing from whatever is already in partPath. If there's an existing download, the
+// server is asked to only send the file if it's changed since, and errNotModified is returned if it hasn't.
+// It returns a record of what was downloaded, apart from its checksum.
+func (d *downloader) attempt(url, partPath string, existing *downloadRecord) (*downloadRecord, error) {
+	file, err := os.OpenFile(partPath, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a record of where the saved part came from, there's no telling whether the rest would match it.
+	partial := readDownloadRecord(partPath)
+	validator := partial.resumeValidator(url)
+	if offset > 0 && validator == "" {
+		if err := file.Truncate(0); err != nil {
+			return nil, err
+		}
+		if offset, err = file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		// If the file has changed, If-Range makes the server send all of it instead of the rest.
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		request.Header.Set("If-Range", validator)
+	} else if existing != nil {
+		if existing.ETag != "" {
+			request.Header.Set("If-None-Match", existing.ETag)
+		}
+		if existing.LastModified != "" {
+			request.Header.Set("If-Modified-Since", existing.LastModified)
+		}
+	}
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	record := &downloadRecord{URL: url, ETag: response.Header.Get("ETag"), LastModified: response.Header.Get("Last-Modified")}
+	total := int64(-1)
+	switch response.StatusCode {
+	case http.StatusNotModified:
+		if existing == nil {
+			return nil, &httpStatusError{code: response.StatusCode, status: response.Status}
+		}
+		return nil, errNotModified
+	case http.StatusOK:
+		// The server sent the whole file, either because nothing was saved yet, because the file changed since the
+		// part was saved, or because it doesn't do ranges.
+		if err := file.Truncate(0); err != nil {
+			return nil, err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		offset = 0
+		if response.ContentLength >= 0 {
+			total = response.ContentLength
+		}
+		// Without this record, the part is started over rather than continued, so failing to save it isn't an error.
+		writeDownloadRecord(partPath, record)
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(response.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return nil, fmt.Errorf("download failed: unexpected Content-Range %q", response.Header.Get("Content-Range"))
+		}
+		// A server that ignores If-Range could still send the rest of a different file.
+		if partial != nil && record.ETag != "" && partial.ETag != "" && record.ETag != partial.ETag {
+			if err := file.Truncate(0); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("the file changed on the server since the download started, so it will be started again")
+		}
+		total = size
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Either the saved part is already the whole file, or it's from a different file. Start over in that case.
+		if _, size, ok := parseContentRange(response.Header.Get("Content-Range")); ok && size == offset {
+			return record, nil
+		}
+		if err := file.Truncate(0); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("the saved part of the download doesn't match the file, so it was discarded")
+	default:
+		return nil, &httpStatusError{code: response.StatusCode, status: response.Status}
+	}
+
+	var body io.Reader = response.Body
+	if d.progress != nil {
+		bar := &progressBar{out: d.progress, total: total, done: offset}
+		defer bar.finish()
+		body = io.TeeReader(response.Body, bar)
+	}
+
+	written, err := io.Copy(file, body)
+	if err != nil {
+		return nil, err
+	}
+	if total >= 0 && offset+written != total {
+		return nil, fmt.Errorf("download ended after %d of %d bytes", offset+written, total)
+	}
+	return record, nil
+}
+
+// parseContentRange reads a Content-Range header such as "bytes 100-199/200" or "bytes */200". The size is -1 if
+// the server didn't say what it is.
+func parseContentRange(header string) (start, size int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	byteRange, sizeText, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+
+	size = -1
+	if sizeText != "*" {
+		var err error
+		if size, err = strconv.ParseInt(sizeText, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if byteRange == "*" {
+		return 0, size, true
+	}
+	startText, _, found := strings.Cut(byteRange, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startText, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+// progressBar draws how much of a download has arrived. It's redrawn at most a few times a second.
+type progressBar struct {
+	out      io.Writer
+	total    int64 // -1 if unknown.
+	done     int64
+	lastDraw time.Time
+}
+
+func (p *progressBar) Write(data []byte) (int, error) {
+	p.done += int64(len(data))
+	if time.Since(p.lastDraw) >= 100*time.Millisecond {
+		p.draw()
+	}
+	return len(data), nil
+}
+
+func (p *progressBar) draw() {
+	p.lastDraw = time.Now()
+	if p.total <= 0 {
+		fmt.Fprintf(p.out, "\r%s downloaded", formatBytes(p.done))
+		return
+	}
+
+	const width = 30
+	filled := int(p.done * width / p.total)
+	fmt.Fprintf(p.out, "\r[%s%s] %3d%% %s / %s", strings.Repeat("#", filled), strings.Repeat(" ", width-filled),
+		p.done*100/p.total, formatBytes(p.done), formatBytes(p.total))
+}
+
+// finish draws the final state of the bar and moves past it.
+func (p *progressBar) finish() {
+	p.draw()
+	fmt.Fprintln(p.out)
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
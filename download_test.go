@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testFile is large enough that a download cut off part way through has something before and after the cut.
+var testFile = bytes.Repeat([]byte("0123456789abcdef"), 4096)
+
+// serveFile answers like a typical web server: with ranges, If-Range, ETag and Last-Modified all supported.
+func serveFile(content []byte, etag string) http.HandlerFunc {
+	modified := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "mpm", modified, bytes.NewReader(content))
+	}
+}
+
+func newTestDownloader() *downloader {
+	d := newDownloader(nil)
+	d.backoff = time.Millisecond
+	return d
+}
+
+func checkDownloaded(t *testing.T, filePath string, want []byte) {
+	t.Helper()
+	got, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("downloaded %d bytes that don't match the %d served", len(got), len(want))
+	}
+	for _, leftover := range []string{filePath + ".part", downloadRecordPath(filePath + ".part")} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("%s was left behind", filepath.Base(leftover))
+		}
+	}
+}
+
+// savePart leaves a partial download behind, as if an earlier run had been stopped part way through.
+func savePart(t *testing.T, filePath string, data []byte, record *downloadRecord) {
+	t.Helper()
+	if err := os.WriteFile(filePath+".part", data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if record != nil {
+		if err := writeDownloadRecord(filePath+".part", record); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(serveFile(testFile, `"v1"`))
+	defer server.Close()
+	filePath := filepath.Join(t.TempDir(), "mpm")
+
+	updated, err := newTestDownloader().download(server.URL, filePath)
+	if err != nil || !updated {
+		t.Fatalf("download() = %v, %v, want true, nil", updated, err)
+	}
+	checkDownloaded(t, filePath, testFile)
+
+	record := readDownloadRecord(filePath)
+	if !record.describes(server.URL, filePath) || record.ETag != `"v1"` {
+		t.Errorf("download record = %+v", record)
+	}
+}
+
+func TestDownloadNotModified(t *testing.T) {
+	var requests atomic.Int32
+	handler := serveFile(testFile, `"v1"`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		handler(w, r)
+	}))
+	defer server.Close()
+	filePath := filepath.Join(t.TempDir(), "mpm")
+
+	if _, err := newTestDownloader().download(server.URL, filePath); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := newTestDownloader().download(server.URL, filePath)
+	if err != nil || updated {
+		t.Fatalf("second download() = %v, %v, want false, nil", updated, err)
+	}
+	checkDownloaded(t, filePath, testFile)
+
+	d := newTestDownloader()
+	d.force = true
+	if updated, err := d.download(server.URL, filePath); err != nil || !updated {
+		t.Fatalf("forced download() = %v, %v, want true, nil", updated, err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("made %d requests, want 3", n)
+	}
+}
+
+func TestDownloadResumesPart(t *testing.T) {
+	var ranges []string
+	handler := serveFile(testFile, `"v1"`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range")+" "+r.Header.Get("If-Range"))
+		handler(w, r)
+	}))
+	defer server.Close()
+	filePath := filepath.Join(t.TempDir(), "mpm")
+
+	savePart(t, filePath, testFile[:1000], &downloadRecord{URL: server.URL, ETag: `"v1"`})
+	if _, err := newTestDownloader().download(server.URL, filePath); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, filePath, testFile)
+	if len(ranges) != 1 || ranges[0] != `bytes=1000- "v1"` {
+		t.Errorf("requests had Range and If-Range %q, want [%q]", ranges, `bytes=1000- "v1"`)
+	}
+}
+
+// A part saved from a file that's since changed on the server mustn't be joined to the rest of the new file.
+func TestDownloadRestartsChangedPart(t *testing.T) {
+	newFile := bytes.Repeat([]byte("fedcba9876543210"), 4096)
+	server := httptest.NewServer(serveFile(newFile, `"v2"`))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		record *downloadRecord
+	}{
+		{"different ETag", &downloadRecord{URL: server.URL, ETag: `"v1"`}},
+		{"different URL", &downloadRecord{URL: server.URL + "/maca64/mpm", ETag: `"v2"`}},
+		{"no record", nil},
+		{"weak ETag only", &downloadRecord{URL: server.URL, ETag: `W/"v2"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "mpm")
+			savePart(t, filePath, testFile[:1000], tt.record)
+			if _, err := newTestDownloader().download(server.URL, filePath); err != nil {
+				t.Fatal(err)
+			}
+			checkDownloaded(t, filePath, newFile)
+		})
+	}
+}
+
+// A server that ignores If-Range sends the rest of the new file. Its ETag gives that away.
+func TestDownloadRestartsWhenIfRangeIgnored(t *testing.T) {
+	newFile := bytes.Repeat([]byte("fedcba9876543210"), 4096)
+	handler := serveFile(newFile, `"v2"`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("If-Range")
+		handler(w, r)
+	}))
+	defer server.Close()
+	filePath := filepath.Join(t.TempDir(), "mpm")
+
+	savePart(t, filePath, testFile[:1000], &downloadRecord{URL: server.URL, ETag: `"v1"`})
+	if _, err := newTestDownloader().download(server.URL, filePath); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, filePath, newFile)
+}
+
+func TestDownloadRetriesDroppedConnection(t *testing.T) {
+	var requests atomic.Int32
+	handler := serveFile(testFile, `"v1"`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			// Promise the whole file, send part of it, then drop the connection.
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(testFile)))
+			w.Write(testFile[:5000])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		case 2:
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+		default:
+			if r.Header.Get("Range") != "bytes=5000-" {
+				t.Errorf("resumed with Range %q, want %q", r.Header.Get("Range"), "bytes=5000-")
+			}
+			handler(w, r)
+		}
+	}))
+	defer server.Close()
+	filePath := filepath.Join(t.TempDir(), "mpm")
+
+	if _, err := newTestDownloader().download(server.URL, filePath); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, filePath, testFile)
+	if n := requests.Load(); n != 3 {
+		t.Errorf("made %d requests, want 3", n)
+	}
+}
+
+func TestDownloadGivesUpOnClientErrors(t *testing.T) {
+	for _, code := range []int{http.StatusNotFound, http.StatusForbidden} {
+		t.Run(strconv.Itoa(code), func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				http.Error(w, http.StatusText(code), code)
+			}))
+			defer server.Close()
+			filePath := filepath.Join(t.TempDir(), "mpm")
+
+			_, err := newTestDownloader().download(server.URL, filePath)
+			var statusErr *httpStatusError
+			if !errors.As(err, &statusErr) || statusErr.code != code {
+				t.Fatalf("download() error = %v, want HTTP %d", err, code)
+			}
+			if n := requests.Load(); n != 1 {
+				t.Errorf("made %d requests, want 1", n)
+			}
+			if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+				t.Error("a file was left at the destination")
+			}
+		})
+	}
+}
+
+func TestDownloadRetriesRunOut(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "busy", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	d := newTestDownloader()
+	if _, err := d.download(server.URL, filepath.Join(t.TempDir(), "mpm")); err == nil {
+		t.Fatal("download() succeeded, want an error")
+	}
+	if n := requests.Load(); n != int32(d.retries+1) {
+		t.Errorf("made %d requests, want %d", n, d.retries+1)
+	}
+}
+
+// A part that already holds the whole file gets a 416 from the server, and is used as it is.
+func TestDownloadCompletePart(t *testing.T) {
+	server := httptest.NewServer(serveFile(testFile, `"v1"`))
+	defer server.Close()
+	filePath := filepath.Join(t.TempDir(), "mpm")
+
+	savePart(t, filePath, testFile, &downloadRecord{URL: server.URL, ETag: `"v1"`})
+	if _, err := newTestDownloader().download(server.URL, filePath); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, filePath, testFile)
+}
+
+// A body that ends before the size the server gave is retried rather than kept.
+func TestDownloadShortBody(t *testing.T) {
+	var requests atomic.Int32
+	handler := serveFile(testFile, `"v1"`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			// A 206 that claims the whole file but only holds part of it.
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Range", "bytes 0-2999/"+strconv.Itoa(len(testFile)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(testFile[:3000])
+			return
+		}
+		handler(w, r)
+	}))
+	defer server.Close()
+	filePath := filepath.Join(t.TempDir(), "mpm")
+
+	if _, err := newTestDownloader().download(server.URL, filePath); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, filePath, testFile)
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header      string
+		start, size int64
+		ok          bool
+	}{
+		{"bytes 100-199/200", 100, 200, true},
+		{"bytes 0-99/*", 0, -1, true},
+		{"bytes */200", 0, 200, true},
+		{"bytes 100-199", 0, 0, false},
+		{"items 0-1/2", 0, 0, false},
+		{"bytes x-199/200", 0, 0, false},
+		{"bytes 0-199/big", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, size, ok := parseContentRange(tt.header)
+		if start != tt.start || size != tt.size || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v, want %d, %d, %v", tt.header, start, size, ok, tt.start, tt.size, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Arguments made only of these characters mean the same thing to the shell without quotes.
+var (
+	bashSafePattern       = regexp.MustCompile(`^[A-Za-z0-9_./=:+@%-]+$`)
+	powerShellSafePattern = regexp.MustCompile(`^[A-Za-z0-9_./\\=:+%-]+$`)
+)
+
+// quoteBash quotes an argument for bash. Single quotes keep everything literal, apart from single quotes themselves.
+func quoteBash(arg string) string {
+	if bashSafePattern.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// quotePowerShell quotes an argument for PowerShell, where a single quote inside single quotes is written twice.
+func quotePowerShell(arg string) string {
+	if powerShellSafePattern.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", "''") + "'"
+}
+
+// bashCommand is a command line that can be pasted into bash.
+func bashCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteBash(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// powerShellCommand is a command line that can be pasted into PowerShell. The call operator lets the program's path
+// be quoted.
+func powerShellCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quotePowerShell(arg)
+	}
+	return "& " + strings.Join(quoted, " ")
+}
+
+// dryRunDirectory says what would happen to a directory that a real run creates if it's missing.
+func dryRunDirectory(path string) string {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path + ", which would be created"
+	}
+	return path
+}
+
+// dryRunDownload stands in for downloading MPM.
+func (s *mpmSession) dryRunDownload() {
+	fmt.Printf("Dry run: MPM would be downloaded as %s to %s.\n", s.mpmBinaryName(), dryRunDirectory(s.mpmDownloadPath))
+}
+
+// printDryRun shows the MPM command that would have been run.
+func (s *mpmSession) printDryRun(cmdArgs []string) {
+	fmt.Println("Dry run: MPM would be run with the following command.")
+	fmt.Println("\nbash:")
+	fmt.Println("  " + bashCommand(cmdArgs))
+	fmt.Println("\nPowerShell:")
+	fmt.Println("  " + powerShellCommand(cmdArgs))
+	fmt.Println()
+}
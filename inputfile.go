@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// mpmInputFile is what this program understands of an MPM input file, the file given to "mpm install --inputfile".
+// Settings are written as key=value, products as product.Name, and anything after a # is a comment. MPM calls the
+// destination "destinationFolder". MathWorks' templates don't have a release setting, but end with a "?checksum" line
+// holding the release they're for, encoded in base64.
+type mpmInputFile struct {
+	release     string
+	destination string
+	products    []string
+
+	// Products listed but commented out. MathWorks' templates list every product this way so that installing one
+	// is a matter of removing its #. They're used to spot a template that hasn't had any products uncommented.
+	commentedProducts []string
+
+	path string
+}
+
+// readMPMInputFile reads an MPM input file. Settings this program doesn't handle are reported as errors rather
+// than being left out of the installation without a word.
+func readMPMInputFile(path string) (*mpmInputFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	input := &mpmInputFile{path: path}
+	var templateRelease string
+	var errs []error
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if commented, ok := strings.CutPrefix(line, "#"); ok {
+			if product, ok := strings.CutPrefix(strings.TrimSpace(commented), "product."); ok {
+				input.commentedProducts = append(input.commentedProducts, strings.TrimSpace(product))
+			}
+			continue
+		}
+
+		if product, ok := strings.CutPrefix(line, "product."); ok {
+			input.products = append(input.products, strings.TrimSpace(product))
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("line %d: %q isn't a setting or a product", lineNumber, line))
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "release":
+			input.release = strings.TrimSpace(value)
+		case "destinationFolder", "destination":
+			input.destination = strings.TrimSpace(value)
+		case "?checksum":
+			release, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+			if err != nil || !releasePattern.Match(release) {
+				errs = append(errs, fmt.Errorf("line %d: the checksum doesn't name a release", lineNumber))
+				continue
+			}
+			templateRelease = string(release)
+		default:
+			errs = append(errs, fmt.Errorf("line %d: the %q setting isn't supported", lineNumber, strings.TrimSpace(key)))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if input.release == "" {
+		input.release = templateRelease
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return input, nil
+}
+
+// mergeInto fills in any option that wasn't already given on the command line or in the answer file. A template whose
+// products are all still commented out is an error when nothing else gives the products, since installing every
+// product instead is unlikely to be what was meant.
+func (input *mpmInputFile) mergeInto(opts *cliOptions) error {
+	if opts.release == "" {
+		opts.release = input.release
+	}
+	if opts.destination == "" {
+		opts.destination = input.destination
+	}
+	if opts.products == "" {
+		if len(input.products) == 0 && len(input.commentedProducts) > 0 {
+			return fmt.Errorf("all %d products in %s are commented out. Remove the # in front of the ones you'd like to install", len(input.commentedProducts), input.path)
+		}
+		opts.products = strings.Join(input.products, " ")
+	}
+	return nil
+}
+
+// writeMPMInputFile saves the selected release, destination and products as an MPM input file. Like MathWorks'
+// templates, every other product for the release is listed too, commented out.
+func (s *mpmSession) writeMPMInputFile(path string) error {
+	var b strings.Builder
+	b.WriteString("########################################################################\n")
+	b.WriteString("## MATLAB Package Manager input file, written by MPM.Go.\n")
+	b.WriteString("## Install with: mpm install --inputfile=<path to this file>\n")
+	b.WriteString("########################################################################\n\n")
+	fmt.Fprintf(&b, "release=%s\n", s.release)
+	fmt.Fprintf(&b, "destinationFolder=%s\n\n", s.installPath)
+
+	b.WriteString("## Products to install. Remove the # in front of a product to install it too.\n")
+	for _, product := range s.products {
+		fmt.Fprintf(&b, "product.%s\n", product)
+	}
+	for _, product := range catalog.productsFor(s.platform, s.release) {
+		if !slices.Contains(s.products, product) {
+			fmt.Fprintf(&b, "#product.%s\n", product)
+		}
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// Longest command line, in characters, that MPM is run with before the products are put in an input file instead.
+// These are kept well below what the systems allow, since an input file works just as well: MPM is started with
+// CreateProcess on Windows, which takes up to 32767 characters, and far more elsewhere. On Windows, a list of every
+// product goes over the threshold.
+var maxCommandLineLength = map[string]int{
+	"windows":  2047,
+	"linux":    32768,
+	"macOSx64": 32768,
+	"macOSARM": 32768,
+}
+
+// commandLineLength estimates how long a command line is once it's joined up, allowing for each argument being quoted.
+func commandLineLength(args []string) int {
+	length := 0
+	for _, arg := range args {
+		length += len(arg) + 3
+	}
+	return length
+}
+
+// temporaryInputFile writes the selections to a new MPM input file in the temporary directory. The caller removes it.
+func (s *mpmSession) temporaryInputFile() (string, error) {
+	file, err := os.CreateTemp("", "mpm_input_*.txt")
+	if err != nil {
+		return "", err
+	}
+	path := file.Name()
+	file.Close()
+	if err := s.writeMPMInputFile(path); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// exportInputFile writes the MPM input file asked for with --export-input-file, if there was one.
+func (s *mpmSession) exportInputFile() error {
+	if s.exportInputPath == "" {
+		return nil
+	}
+	if err := s.writeMPMInputFile(s.exportInputPath); err != nil {
+		return fmt.Errorf("error writing the MPM input file: %w", err)
+	}
+	fmt.Println("MPM input file written to " + s.exportInputPath)
+	return nil
+}
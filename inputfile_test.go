@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadMPMInputFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		release     string
+		destination string
+		products    []string
+		err         string
+	}{
+		{
+			name: "template",
+			file: "## MATLAB Package Manager Input File\n# destinationFolder=\ndestinationFolder=/opt/matlab\n\n" +
+				"product.MATLAB\n#product.Simulink\n\n?checksum=UjIwMjRi\n",
+			release:     "R2024b",
+			destination: "/opt/matlab",
+			products:    []string{"MATLAB"},
+		},
+		{
+			name:        "release setting wins over the checksum",
+			file:        "release=R2025a\n?checksum=UjIwMjRi\ndestination=/opt/matlab\nproduct.MATLAB\n",
+			release:     "R2025a",
+			destination: "/opt/matlab",
+			products:    []string{"MATLAB"},
+		},
+		{
+			name: "checksum that isn't a release",
+			file: "product.MATLAB\n?checksum=bm90IGEgcmVsZWFzZQ==\n",
+			err:  "line 2: the checksum doesn't name a release",
+		},
+		{
+			name: "unknown setting",
+			file: "release=R2024b\nagreeToLicense=yes\n",
+			err:  `line 2: the "agreeToLicense" setting isn't supported`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mpm_input.txt")
+			if err := os.WriteFile(path, []byte(tt.file), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			input, err := readMPMInputFile(path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("readMPMInputFile() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if input.release != tt.release || input.destination != tt.destination || !slices.Equal(input.products, tt.products) {
+				t.Errorf("read %q, %q, %v, want %q, %q, %v", input.release, input.destination, input.products, tt.release, tt.destination, tt.products)
+			}
+		})
+	}
+}
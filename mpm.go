@@ -1,14 +1,17 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"syscall"
 
@@ -24,6 +27,7 @@ type customWriter struct {
 // mpmSession holds all state accumulated during the interactive CLI session.
 type mpmSession struct {
 	rl        *readline.Instance
+	completer *promptCompleter
 	redText   func(a ...any) string
 	greenText func(a ...any) string
 
@@ -32,25 +36,34 @@ type mpmSession struct {
 	mpmURL          string
 	mpmDownloadPath string
 	mpmFullPath     string
+	mpmChecksum     string // Expected SHA-256 of MPM, if one was given.
+	mpmManifestPath string // JSON file of expected SHA-256 checksums by platform.
+	mpmSource       string // Mirror URL, file URL or path to get MPM from instead of MathWorks.
+	network         networkSettings
+	forceDownload   bool   // Download MPM even if the existing copy is current.
+	dryRun          bool   // Go through every step without downloading, creating or installing anything.
+	exportInputPath string // Where to save the selections as an MPM input file.
+	mpm             mpmProcess
 
 	release       string
 	validReleases []string
 	products      []string
+	bundles       map[string]productBundle // Keyed by lowercase name.
 
 	installPath string
 	licensePath string
 	licenseUsed bool
-}
 
-// allReleaseOrder defines the chronological order of all supported releases.
-var allReleaseOrder = []string{
-	"R2017b", "R2018a", "R2018b", "R2019a", "R2019b", "R2020a", "R2020b",
-	"R2021a", "R2021b", "R2022a", "R2022b", "R2023a", "R2023b", "R2024a", "R2024b", "R2025a", "R2025b",
+	// Set when the session is driven by command-line options. Nothing is ever read from stdin in this mode.
+	nonInteractive bool
+	archChoice     string // "intel" or "arm", only used on Apple Silicon Macs.
+	resuming       bool   // Whether a saved session is being resumed and the steps it finished are being skipped.
 }
 
+// releaseIndexMap gives the chronological position of every release in the catalog.
 var releaseIndexMap = func() map[string]int {
-	m := make(map[string]int, len(allReleaseOrder))
-	for i, r := range allReleaseOrder {
+	m := make(map[string]int, len(catalog.Releases))
+	for i, r := range catalog.Releases {
 		m[r] = i
 	}
 	return m
@@ -61,11 +74,10 @@ func releaseIndex(r string) int {
 }
 
 func newSession() (*mpmSession, error) {
+	completer := &promptCompleter{current: fileCompleter}
 	rl, err := readline.NewEx(&readline.Config{
-		Prompt: "> ",
-		AutoComplete: readline.NewPrefixCompleter(
-			readline.PcItemDynamic(listFiles),
-		),
+		Prompt:       "> ",
+		AutoComplete: completer,
 	})
 	if err != nil {
 		return nil, err
@@ -73,30 +85,40 @@ func newSession() (*mpmSession, error) {
 
 	s := &mpmSession{
 		rl:        rl,
+		completer: completer,
 		redText:   color.New(color.FgRed).SprintFunc(),
 		greenText: color.New(color.FgHiGreen).SprintFunc(),
 	}
 
-	// Setup for better Ctrl+C messaging.
+	// Setup for better Ctrl+C messaging. While MPM is running, it's stopped first, and runMPM reports it.
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		<-signalChan
-		fmt.Println(s.redText("\nExiting from user input."))
-		os.Exit(0)
+		for sig := range signalChan {
+			if !s.mpm.stop(sig, s.redText) {
+				fmt.Println(s.redText("\nExiting from user input."))
+				os.Exit((&interruptedError{sig}).exitCode())
+			}
+		}
 	}()
 
 	return s, nil
 }
 
 func main() {
+	runSubcommand(os.Args[1:])
+
+	opts, err := parseOptions(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	} else if err != nil {
+		os.Exit(2)
+	}
+
 	// Print version number, if requested.
-	args := os.Args[1:]
-	for _, arg := range args {
-		if arg == "-version" {
-			fmt.Println("Version number: 2.1")
-			os.Exit(0)
-		}
+	if opts.showVersion {
+		fmt.Println("Version number: 2.1")
+		os.Exit(0)
 	}
 
 	s, err := newSession()
@@ -105,27 +127,116 @@ func main() {
 	}
 	defer s.rl.Close()
 
-	steps := []func() error{
-		s.detectPlatform,
-		s.selectAndDownloadMPM,
-		s.selectRelease,
-		s.selectProducts,
-		s.selectInstallPath,
-		s.selectLicenseFile,
-		s.runMPM,
-		s.installLicenseFile,
-	}
-	for _, step := range steps {
-		if err := step(); err != nil {
+	if opts.nonInteractive() {
+		if err := s.applyOptions(opts); err != nil {
+			fmt.Println(s.redText(err.Error()))
+			os.Exit(1)
+		}
+	}
+	s.mpmChecksum = opts.mpmSHA256
+	s.mpmManifestPath = opts.mpmManifest
+	s.mpmSource = opts.mpmSource
+	s.network = networkSettings{proxy: opts.proxy, noProxy: opts.noProxy, caBundle: opts.caBundle}
+	s.forceDownload = opts.forceDownload
+	s.dryRun = opts.dryRun
+	s.exportInputPath = opts.exportInputFile
+	s.mpm.gracePeriod = opts.gracePeriod
+	if err := s.loadBundles(opts.bundlesPath); err != nil {
+		fmt.Println(s.redText(err.Error()))
+		os.Exit(1)
+	}
+
+	// Typing "back" at a question goes back to the last step before it that asks something.
+	steps := []wizardStep{
+		{"platform", s.detectPlatform, canChooseArch},
+		{"options", s.validateOptions, false},
+		{"mpm", s.selectAndDownloadMPM, true},
+		{"release", s.selectRelease, true},
+		{"products", s.selectProducts, true},
+		{"destination", s.selectInstallPath, true},
+		{"license", s.selectLicenseFile, true},
+		{"review", s.reviewSelections, true},
+		{"export", s.exportInputFile, false},
+		{"install", s.runMPM, false},
+		{"license-copy", s.installLicenseFile, false},
+	}
+
+	// When resuming, the steps that were finished are skipped, apart from the ones that set up the session.
+	resumeFrom := 0
+	if opts.resume {
+		resumeFrom, err = s.resumeState(steps)
+		if err != nil {
 			fmt.Println(s.redText(err.Error()))
 			os.Exit(1)
 		}
+		s.resuming = resumeFrom > 0
 	}
 
+	for i := 0; i < len(steps); i++ {
+		if i < resumeFrom && !slices.Contains(setupSteps, steps[i].name) {
+			continue
+		}
+		if i == resumeFrom {
+			// From here on, going back returns to skipped steps as usual.
+			resumeFrom = 0
+			s.resuming = false
+		}
+
+		err := steps[i].run()
+		if err == errGoBack {
+			previous := i - 1
+			for previous >= 0 && !steps[previous].asks {
+				previous--
+			}
+			if previous < 0 {
+				fmt.Println(s.redText("There's no earlier question to go back to."))
+				previous = i
+			}
+			i = previous - 1 // Steps in between are run again on the way forward.
+			continue
+		}
+		var interrupted *interruptedError
+		if errors.As(err, &interrupted) {
+			fmt.Println(s.redText(err.Error()))
+			os.Exit(interrupted.exitCode())
+		}
+		if err != nil {
+			fmt.Println(s.redText(err.Error()))
+			if s.dryRun {
+				fmt.Println("Dry run: a real run would stop here and exit with status 1.")
+			}
+			os.Exit(1)
+		}
+		// Until the step being resumed from is reached, the saved session still says where to carry on from.
+		if resumeFrom == 0 && i+1 < len(steps) {
+			s.saveState(steps[i+1].name)
+		}
+	}
+	s.removeState()
+
+	if s.dryRun {
+		fmt.Println(s.greenText("Dry run finished. Everything checked out, so a real run would go on to run MPM, and exit with status 0 if MPM succeeds."))
+		if !s.nonInteractive {
+			fmt.Println("Press the Enter/Return key to close this program.")
+			ExitHelper(s.rl)
+		}
+		return
+	}
+	if s.nonInteractive {
+		fmt.Println(s.greenText("Installation finished!"))
+		return
+	}
 	fmt.Println(s.greenText("Installation finished! Press the Enter/Return key to close this program."))
 	ExitHelper(s.rl)
 }
 
+// wizardStep is one step of the installation, run in order by main.
+type wizardStep struct {
+	name string // Saved with the session, to say which step to resume from.
+	run  func() error
+	asks bool // Whether the step can ask a question, so that "back" can return to it.
+}
+
 // Figure out your OS.
 func (s *mpmSession) detectPlatform() error {
 	switch runtime.GOOS {
@@ -138,11 +249,26 @@ func (s *mpmSession) detectPlatform() error {
 		case "arm64":
 			s.platform = "macOSARM"
 
+			if s.nonInteractive {
+				if !s.setMacArch(s.archChoice) {
+					return fmt.Errorf("invalid or missing --arch %q. Enter either intel or arm", s.archChoice)
+				}
+				break
+			}
+
+			// A resumed session keeps the choice made before.
+			if s.resuming && s.setMacArch(s.archChoice) {
+				break
+			}
+
 			// Ask macOSARM users which installer they'd like to use.
+			s.completeWords(func() []string { return []string{"intel", "arm", "idk"} })
 			for {
 				fmt.Println("Would you like to install an Intel or ARM version of your products? Type in \"intel\", \"arm\" or \"idk\" if you're unsure.")
 				manualOSspecified, err := readUserInput(s.rl)
-				if err != nil {
+				if err == errGoBack {
+					return err
+				} else if err != nil {
 					if err.Error() == "Interrupt" {
 						fmt.Println(s.redText("Exiting from user input."))
 					} else {
@@ -152,20 +278,11 @@ func (s *mpmSession) detectPlatform() error {
 					return err
 				}
 
-				manualOSspecified = strings.ToLower(strings.TrimSpace(manualOSspecified))
-
-				// Haha yes, I will make you use Intel if you literally type in "idk".
-				switch manualOSspecified {
-				case "intel", "\"intel\"", "idk", "\"idk\"":
-					s.mpmURL = "https://www.mathworks.com/mpm/maci64/mpm"
-					s.platform = "macOSx64"
-				case "arm", "\"arm\"":
-					s.mpmURL = "https://www.mathworks.com/mpm/maca64/mpm"
-					s.platform = "macOSARM"
-				default:
+				if !s.setMacArch(manualOSspecified) {
 					fmt.Println(s.redText("Invalid selection. Enter either intel, arm, or idk."))
 					continue
 				}
+				s.archChoice = manualOSspecified
 				break
 			}
 		}
@@ -189,22 +306,92 @@ func (s *mpmSession) detectPlatform() error {
 		s.defaultTMP = "/tmp"
 		s.mpmURL = "https://www.mathworks.com/mpm/glnxa64/mpm"
 	default:
+		if s.nonInteractive {
+			return fmt.Errorf("your operating system is unrecognized")
+		}
 		fmt.Println(s.redText("Your operating system is unrecognized. Press Enter/Return on your keyboard to close this program."))
 		ExitHelper(s.rl)
 	}
 	return nil
 }
 
+// setMacArch picks the Intel or ARM version of MPM on Apple Silicon Macs. It returns false for an unknown choice.
+func (s *mpmSession) setMacArch(choice string) bool {
+	choice = strings.ToLower(strings.TrimSpace(choice))
+
+	// Haha yes, I will make you use Intel if you literally type in "idk".
+	switch choice {
+	case "intel", "\"intel\"", "idk", "\"idk\"":
+		s.mpmURL = "https://www.mathworks.com/mpm/maci64/mpm"
+		s.platform = "macOSx64"
+	case "arm", "\"arm\"":
+		s.mpmURL = "https://www.mathworks.com/mpm/maca64/mpm"
+		s.platform = "macOSARM"
+	default:
+		return false
+	}
+	return true
+}
+
 // Figure out where you want actual MPM to go and download it.
 func (s *mpmSession) selectAndDownloadMPM() error {
+	// validateOptions has checked this already, but the platform can change since then on Apple Silicon Macs.
+	if _, err := s.expectedMPMChecksum(); err != nil {
+		return err
+	}
+
 	mpmDownloadNeeded := true
 	mpmTypeIsMismatched := false
 
+	// Without prompts, always make sure MPM is current so it knows about the requested release.
+	if s.nonInteractive {
+		if s.mpmDownloadPath == "" {
+			s.mpmDownloadPath, _ = s.defaultMPMDownloadPath()
+		}
+		if s.dryRun {
+			s.dryRunDownload()
+			return nil
+		}
+		if err := os.MkdirAll(s.mpmDownloadPath, 0755); err != nil {
+			return fmt.Errorf("failed to create the MPM download directory: %w", err)
+		}
+
+		fmt.Println("Downloading MPM. Please wait.")
+		fileName := filepath.Join(s.mpmDownloadPath, s.mpmBinaryName())
+		updated, err := s.fetchMPM(fileName)
+		if err != nil {
+			return fmt.Errorf("failed to download MPM: %w", err)
+		}
+		if err := s.verifyMPM(fileName); err != nil {
+			return err
+		}
+		if updated {
+			fmt.Println("MPM downloaded successfully.")
+		} else {
+			fmt.Println("Your copy of MPM is already up to date.")
+		}
+
+		if err := s.makeMPMExecutable(); err != nil {
+			return fmt.Errorf("failed to make MPM executable: %w", err)
+		}
+		return nil
+	}
+
+	// The cache is managed by this program, so MPM is kept up to date there without asking.
+	defaultPath, cached := s.defaultMPMDownloadPath()
+	if cached {
+		defaultPath += " (the MPM cache)"
+	}
+
+	s.completeFiles()
+downloadPathPrompt:
 	for {
 		fmt.Print("Enter the path to where you would like MPM to download to. " +
-			"Press Enter to use \"" + s.defaultTMP + "\"\n> ")
+			"Press Enter to use \"" + defaultPath + "\"\n> ")
 		mpmDownloadPath, err := readUserInput(s.rl)
-		if err != nil {
+		if err == errGoBack {
+			return err
+		} else if err != nil {
 			if err.Error() == "Interrupt" {
 				fmt.Println(s.redText("Exiting from user input."))
 			} else {
@@ -215,14 +402,30 @@ func (s *mpmSession) selectAndDownloadMPM() error {
 		}
 		mpmDownloadPath = strings.TrimSpace(mpmDownloadPath)
 
+		if s.dryRun {
+			if mpmDownloadPath == "" {
+				mpmDownloadPath, _ = s.defaultMPMDownloadPath()
+			}
+			s.mpmDownloadPath = mpmDownloadPath
+			s.dryRunDownload()
+			return nil
+		}
+
+		useCache := false
 		if mpmDownloadPath == "" {
-			mpmDownloadPath = s.defaultTMP
+			mpmDownloadPath, useCache = s.defaultMPMDownloadPath()
+			if err := os.MkdirAll(mpmDownloadPath, 0755); err != nil {
+				fmt.Println(s.redText("Failed to create the MPM cache: ", err, ". Please select a different directory."))
+				continue
+			}
 		} else {
 			_, err := os.Stat(mpmDownloadPath)
 			if os.IsNotExist(err) {
 				fmt.Printf("The directory \"%s\" does not exist. Do you want to create it? (y/n)\n> ", mpmDownloadPath)
 				createDir, err := readUserInput(s.rl)
-				if err != nil {
+				if err == errGoBack {
+					continue // Ask for the download path again.
+				} else if err != nil {
 					if err.Error() == "Interrupt" {
 						fmt.Println(s.redText("Exiting from user input."))
 					} else {
@@ -254,45 +457,29 @@ func (s *mpmSession) selectAndDownloadMPM() error {
 		s.mpmDownloadPath = mpmDownloadPath
 
 		// Check if MPM already exists in the selected directory.
-		fileName := filepath.Join(mpmDownloadPath, "mpm")
-		if s.platform == "windows" {
-			fileName = filepath.Join(mpmDownloadPath, "mpm.exe")
-		}
+		fileName := filepath.Join(mpmDownloadPath, s.mpmBinaryName())
 		_, err = os.Stat(fileName)
 		for {
-			if err == nil {
-				if s.platform == "macOSARM" || s.platform == "macOSx64" {
-					fmt.Print("An existing copy of MPM has been detected. Checking which version you downloaded, please wait.\n\n")
-					cmd := exec.Command("lipo", "-info", fileName)
-					output, err := cmd.Output()
-					if err != nil {
-						fmt.Println(s.redText("Error checking MPM's file architecture: ", err, ". Please move or delete your existing copy of MPM from the selected directory before proceeding. "+
-							"You likely either have a corrupted copy of MPM or it is for Windows or Linux. Press Enter/Return on your keyboard to close this program."))
-						ExitHelper(s.rl)
-					}
-					archInfo := string(output)
-
-					// Warn users if their copy of MPM doesn't match their selected CPU type.
-					if strings.Contains(archInfo, "arm64") {
-						if s.platform == "macOSx64" {
-							mpmTypeIsMismatched = true
-						}
-					} else if strings.Contains(archInfo, "x86_64") {
-						if s.platform == "macOSARM" {
-							mpmTypeIsMismatched = true
-						}
-					} else {
-						fmt.Println(s.redText("Error checking MPM's file architecture. Please move or delete your existing copy of MPM from the selected directory before proceeding. Press Enter/Return on your keyboard to close this program."))
-						ExitHelper(s.rl)
-					}
-				}
-				if mpmTypeIsMismatched {
-					fmt.Println("MPM already exists in this directory and is for a different CPU architecture than you selected. Would you like to overwrite it?")
+			if err == nil && !useCache {
+				// Warn users if their copy of MPM is for another OS or doesn't match their selected CPU type.
+				platforms, description, err := executablePlatforms(fileName)
+				if err != nil {
+					mpmTypeIsMismatched = true
+					fmt.Printf("MPM already exists in this directory, but it can't be used: %v. Would you like to overwrite it?\n", err)
+				} else if !slices.Contains(platforms, s.platform) {
+					mpmTypeIsMismatched = true
+					fmt.Printf("MPM already exists in this directory, but it is %s rather than MPM for %s. Would you like to overwrite it?\n", description, s.platform)
+				} else if s.mpmIsTracked(fileName) {
+					// No need to ask. It's only replaced if there's a newer version.
+					fmt.Println("MPM already exists in this directory. Checking whether there's a newer version.")
+					break
 				} else {
 					fmt.Println("MPM already exists in this directory. Would you like to overwrite it?")
 				}
 				overwriteMPM, err := readUserInput(s.rl)
-				if err != nil {
+				if err == errGoBack {
+					continue downloadPathPrompt
+				} else if err != nil {
 					if err.Error() == "Interrupt" {
 						fmt.Println(s.redText("Exiting from user input."))
 					} else {
@@ -306,7 +493,7 @@ func (s *mpmSession) selectAndDownloadMPM() error {
 
 				if overwriteMPM == "n" || overwriteMPM == "no" || overwriteMPM == "f" || overwriteMPM == "false" {
 					if mpmTypeIsMismatched { // Make up your mind. Do you want to use ARM or Intel?
-						fmt.Println(s.redText("You can't use a version of MPM that doesn't match the CPU architecture you selected. Please either select a different directory to download " +
+						fmt.Println(s.redText("You can't use a copy of MPM that doesn't match the platform and CPU architecture you selected. Please either select a different directory to download " +
 							"MPM or move your existing copy elsewhere. Press Enter/Return on your keyboard to close this program."))
 						ExitHelper(s.rl)
 					} else {
@@ -329,51 +516,77 @@ func (s *mpmSession) selectAndDownloadMPM() error {
 		// Download MPM.
 		if mpmDownloadNeeded {
 			fmt.Println("Downloading MPM. Please wait.")
-			err = downloadFile(s.mpmURL, fileName)
+			updated, err := s.fetchMPM(fileName)
 			if err != nil {
 				fmt.Println(s.redText("Failed to download MPM. ", err))
 				os.Exit(1)
 			}
-			fmt.Println("MPM downloaded successfully.")
+			if err := s.verifyMPM(fileName); err != nil {
+				fmt.Println(s.redText(err.Error()))
+				os.Exit(1)
+			}
+			if updated {
+				fmt.Println("MPM downloaded successfully.")
+			} else {
+				fmt.Println("Your copy of MPM is already up to date.")
+			}
 		}
 
-		// Make sure you can actually execute MPM on Linux and macOS.
-		if s.platform != "windows" {
-			cmd := exec.Command("chmod", "+x", filepath.Join(mpmDownloadPath, "mpm"))
-			err := cmd.Run()
-
-			if err != nil {
-				fmt.Println("Failed to execute the command: ", err)
-				fmt.Print(". Either select a different directory, run this program with needed privileges, " +
-					"or make modifications to MPM outside of this program.")
-				continue
-			}
+		if err := s.makeMPMExecutable(); err != nil {
+			fmt.Println("Failed to execute the command: ", err)
+			fmt.Print(". Either select a different directory, run this program with needed privileges, " +
+				"or make modifications to MPM outside of this program.")
+			continue
 		}
 		break
 	}
 	return nil
 }
 
+// mpmBinaryName is MPM's file name on the selected platform.
+func (s *mpmSession) mpmBinaryName() string {
+	if s.platform == "windows" {
+		return "mpm.exe"
+	}
+	return "mpm"
+}
+
+// Make sure you can actually execute MPM on Linux and macOS.
+func (s *mpmSession) makeMPMExecutable() error {
+	if s.platform == "windows" {
+		return nil
+	}
+	cmd := exec.Command("chmod", "+x", filepath.Join(s.mpmDownloadPath, "mpm"))
+	return cmd.Run()
+}
+
 // Ask the user which release they'd like to install.
 func (s *mpmSession) selectRelease() error {
-	if s.platform == "macOSARM" {
-		s.validReleases = []string{
-			"R2023b", "R2024a", "R2024b", "R2025a", "R2025b",
-		}
-	} else {
-		s.validReleases = []string{
-			"R2017b", "R2018a", "R2018b", "R2019a", "R2019b", "R2020a", "R2020b",
-			"R2021a", "R2021b", "R2022a", "R2022b", "R2023a", "R2023b", "R2024a", "R2024b", "R2025a", "R2025b",
+	s.setValidReleases()
+	defaultRelease := catalog.DefaultRelease
+
+	if s.nonInteractive {
+		release, ok := s.matchRelease(s.release)
+		if !ok {
+			return fmt.Errorf("invalid release %q. %s", s.release, s.releaseRangeHint())
 		}
+		s.release = release
+		return nil
 	}
 
-	defaultRelease := "R2025b"
+	// After going back, the release chosen before is the default.
+	if s.release != "" {
+		defaultRelease = s.release
+	}
 
+	s.completeWords(func() []string { return s.validReleases })
 	for {
 		fmt.Printf("Enter which release you would like to install. Press Enter to select %s: ", defaultRelease)
 		fmt.Print("\n> ")
 		release, err := readUserInput(s.rl)
-		if err != nil {
+		if err == errGoBack {
+			return err
+		} else if err != nil {
 			if err.Error() == "Interrupt" {
 				fmt.Println(s.redText("Exiting from user input."))
 			} else {
@@ -388,37 +601,74 @@ func (s *mpmSession) selectRelease() error {
 			release = defaultRelease
 		}
 
-		release = strings.ToLower(release)
-		found := false
-		for _, validRelease := range s.validReleases {
-			if strings.ToLower(validRelease) == release {
-				release = validRelease
-				found = true
-				break
-			}
-		}
-
-		if found {
-			s.release = release
+		if validRelease, found := s.matchRelease(release); found {
+			s.release = validRelease
 			break
 		}
 
-		if s.platform == "macOSARM" {
-			fmt.Println(s.redText("Invalid release. Enter a release between R2023b-R2025b."))
-		} else {
-			fmt.Println(s.redText("Invalid release. Enter a release between R2017b-R2025b."))
-		}
+		fmt.Println(s.redText("Invalid release. " + s.releaseRangeHint()))
 	}
 	return nil
 }
 
+// setValidReleases lists the releases MPM can install on the selected platform.
+func (s *mpmSession) setValidReleases() {
+	s.validReleases = catalog.releasesFor(s.platform)
+}
+
+// matchRelease finds release in validReleases, ignoring case, and returns its canonical spelling.
+func (s *mpmSession) matchRelease(release string) (string, bool) {
+	release = strings.ToLower(strings.TrimSpace(release))
+	for _, validRelease := range s.validReleases {
+		if strings.ToLower(validRelease) == release {
+			return validRelease, true
+		}
+	}
+	return "", false
+}
+
+func (s *mpmSession) releaseRangeHint() string {
+	releases := catalog.releasesFor(s.platform)
+	if len(releases) == 0 {
+		return "No releases are available for your platform."
+	}
+	return fmt.Sprintf("Enter a release between %s-%s.", releases[0], releases[len(releases)-1])
+}
+
 // Product selection and validation.
 func (s *mpmSession) selectProducts() error {
+	allProducts := catalog.productsFor(s.platform, s.release)
+
+	if s.nonInteractive {
+		products, expansions, unresolved, leftOut := s.productsFromInput(s.products, allProducts)
+		s.printPatternExpansions(expansions)
+		s.printLeftOut(leftOut)
+		if len(unresolved) > 0 {
+			s.printUnresolvedProducts(unresolved)
+			return fmt.Errorf("%d product(s) can't be installed", len(unresolved))
+		}
+		products, err := s.withDependencies(products)
+		if err != nil {
+			return err
+		}
+		s.products = products
+		return nil
+	}
+
+	s.completeWords(s.productCompletions)
 	for {
 		fmt.Print("Enter the products you would like to install. Use the same syntax as MPM to specify products. " +
-			"Press Enter to install all products.\n> ")
+			"Press Enter to install all products. Put a \"-\" in front of a product to leave it out, such as \"all -Polyspace_Test\". " +
+			"Type \"pick\" to choose from a list.")
+		// After going back, the earlier selection can be kept. It's checked again, since the release may have changed.
+		if len(s.products) > 0 {
+			fmt.Printf(" Type \"keep\" to keep your current selection of %d product(s).", len(s.products))
+		}
+		fmt.Print("\n> ")
 		productsInput, err := readUserInput(s.rl)
-		if err != nil {
+		if err == errGoBack {
+			return err
+		} else if err != nil {
 			if err.Error() == "Interrupt" {
 				fmt.Println(s.redText("Exiting from user input."))
 			} else {
@@ -428,178 +678,202 @@ func (s *mpmSession) selectProducts() error {
 			return err
 		}
 
-		productsInput = strings.TrimSpace(productsInput)
-
-		// Begin assembling the full product list based on your release and platform.
-		// This is to ensure the products you're specifying exist or that a full list is assembled if you decide to install everything.
-		// Notes:
-		// - No oldProductsToAdd is needed for macOSARM at the moment (apart from R2024b).
-		// - No new products were added in R2024a, R2024b, R2025a, nor R2025b for any platform, so they are omitted entries.
-		var newProductsToAdd map[string]string
-		var oldProductsToAdd map[string]string
-		var allProducts []string
-
-		// Let's start with defining the "new" products to add.
-		switch s.platform {
-		case "windows":
-			newProductsToAdd = map[string]string{
-				"R2023b": "Simulink_Fault_Analyzer Polyspace_Test",
-				"R2023a": "MATLAB_Test C2000_Microcontroller_Blockset",
-				"R2022b": "Medical_Imaging_Toolbox Simscape_Battery",
-				"R2022a": "Wireless_Testbench Bluetooth_Toolbox DSP_HDL_Toolbox Requirements_Toolbox Industrial_Communication_Toolbox",
-				"R2021b": "Signal_Integrity_Toolbox RF_PCB_Toolbox",
-				"R2021a": "Satellite_Communications_Toolbox DDS_Blockset",
-				"R2020b": "UAV_Toolbox Radar_Toolbox Lidar_Toolbox Deep_Learning_HDL_Toolbox",
-				"R2020a": "Simulink_Compiler Motor_Control_Blockset MATLAB_Web_App_Server Wireless_HDL_Toolbox",
-				"R2019b": "ROS_Toolbox Navigation_Toolbox",
-				"R2019a": "System_Composer SoC_Blockset SerDes_Toolbox Reinforcement_Learning_Toolbox Audio_Toolbox Mixed-Signal_Blockset AUTOSAR_Blockset MATLAB_Parallel_Server Polyspace_Bug_Finder_Server Polyspace_Code_Prover_Server Automated_Driving_Toolbox Computer_Vision_Toolbox",
-				"R2018b": "Communications_Toolbox Simscape_Electrical Sensor_Fusion_and_Tracking_Toolbox Deep_Learning_Toolbox 5G_Toolbox WLAN_Toolbox LTE_Toolbox",
-				"R2018a": "Predictive_Maintenance_Toolbox Vehicle_Dynamics_Blockset",
-				"R2017b": "Aerospace_Blockset Aerospace_Toolbox Antenna_Toolbox Bioinformatics_Toolbox Control_System_Toolbox Curve_Fitting_Toolbox DSP_System_Toolbox Data_Acquisition_Toolbox Database_Toolbox Datafeed_Toolbox Econometrics_Toolbox Embedded_Coder Financial_Instruments_Toolbox Financial_Toolbox Fixed-Point_Designer Fuzzy_Logic_Toolbox GPU_Coder Global_Optimization_Toolbox HDL_Coder HDL_Verifier Image_Acquisition_Toolbox Image_Processing_Toolbox Instrument_Control_Toolbox MATLAB MATLAB_Coder MATLAB_Compiler MATLAB_Compiler_SDK MATLAB_Production_Server MATLAB_Report_Generator Mapping_Toolbox Model_Predictive_Control_Toolbox Model-Based_Calibration_Toolbox Network_License_Manager Optimization_Toolbox Parallel_Computing_Toolbox Partial_Differential_Equation_Toolbox Phased_Array_System_Toolbox Polyspace_Bug_Finder Polyspace_Code_Prover Powertrain_Blockset RF_Blockset RF_Toolbox Risk_Management_Toolbox Robotics_System_Toolbox Robust_Control_Toolbox Signal_Processing_Toolbox SimBiology SimEvents Simscape Simscape_Driveline Simscape_Fluids Simscape_Multibody Simulink Simulink_3D_Animation Simulink_Check Simulink_Coder Simulink_Control_Design Simulink_Coverage Simulink_Design_Optimization Simulink_Design_Verifier Simulink_Desktop_Real-Time Simulink_PLC_Coder Simulink_Real-Time Simulink_Report_Generator Simulink_Test Spreadsheet_Link Stateflow Statistics_and_Machine_Learning_Toolbox Symbolic_Math_Toolbox System_Identification_Toolbox Text_Analytics_Toolbox Vehicle_Network_Toolbox Vision_HDL_Toolbox Wavelet_Toolbox",
+		if len(s.products) > 0 && strings.EqualFold(strings.TrimSpace(productsInput), "keep") {
+			if s.recheckProducts() {
+				break
 			}
+			continue
+		}
 
-		case "linux":
-			newProductsToAdd = map[string]string{
-				"R2023b": "Simulink_Fault_Analyzer Polyspace_Test Simulink_Desktop_Real-Time",
-				"R2023a": "MATLAB_Test C2000_Microcontroller_Blockset",
-				"R2022b": "Medical_Imaging_Toolbox Simscape_Battery",
-				"R2022a": "Wireless_Testbench Simulink_Real-Time Bluetooth_Toolbox DSP_HDL_Toolbox Requirements_Toolbox Industrial_Communication_Toolbox",
-				"R2021b": "Signal_Integrity_Toolbox RF_PCB_Toolbox",
-				"R2021a": "Satellite_Communications_Toolbox DDS_Blockset",
-				"R2020b": "UAV_Toolbox Radar_Toolbox Lidar_Toolbox Deep_Learning_HDL_Toolbox",
-				"R2020a": "Simulink_Compiler Motor_Control_Blockset MATLAB_Web_App_Server Wireless_HDL_Toolbox",
-				"R2019b": "ROS_Toolbox Simulink_PLC_Coder Navigation_Toolbox",
-				"R2019a": "System_Composer SoC_Blockset SerDes_Toolbox Reinforcement_Learning_Toolbox Audio_Toolbox Mixed-Signal_Blockset AUTOSAR_Blockset MATLAB_Parallel_Server Polyspace_Bug_Finder_Server Polyspace_Code_Prover_Server Automated_Driving_Toolbox Computer_Vision_Toolbox",
-				"R2018b": "Communications_Toolbox Simscape_Electrical Sensor_Fusion_and_Tracking_Toolbox Deep_Learning_Toolbox 5G_Toolbox WLAN_Toolbox LTE_Toolbox",
-				"R2018a": "Predictive_Maintenance_Toolbox Vehicle_Network_Toolbox Vehicle_Dynamics_Blockset",
-				"R2017b": "Aerospace_Blockset Aerospace_Toolbox Antenna_Toolbox Bioinformatics_Toolbox Control_System_Toolbox Curve_Fitting_Toolbox DSP_System_Toolbox Database_Toolbox Datafeed_Toolbox Econometrics_Toolbox Embedded_Coder Financial_Instruments_Toolbox Financial_Toolbox Fixed-Point_Designer Fuzzy_Logic_Toolbox GPU_Coder Global_Optimization_Toolbox HDL_Coder HDL_Verifier Image_Acquisition_Toolbox Image_Processing_Toolbox Instrument_Control_Toolbox MATLAB MATLAB_Coder MATLAB_Compiler MATLAB_Compiler_SDK MATLAB_Production_Server MATLAB_Report_Generator Mapping_Toolbox Model_Predictive_Control_Toolbox Network_License_Manager Optimization_Toolbox Parallel_Computing_Toolbox Partial_Differential_Equation_Toolbox Phased_Array_System_Toolbox Polyspace_Bug_Finder Polyspace_Code_Prover Powertrain_Blockset RF_Blockset RF_Toolbox Risk_Management_Toolbox Robotics_System_Toolbox Robust_Control_Toolbox Signal_Processing_Toolbox SimBiology SimEvents Simscape Simscape_Driveline Simscape_Fluids Simscape_Multibody Simulink Simulink_3D_Animation Simulink_Check Simulink_Coder Simulink_Control_Design Simulink_Coverage Simulink_Design_Optimization Simulink_Design_Verifier Simulink_Report_Generator Simulink_Test Stateflow Statistics_and_Machine_Learning_Toolbox Symbolic_Math_Toolbox System_Identification_Toolbox Text_Analytics_Toolbox Vision_HDL_Toolbox Wavelet_Toolbox",
+		if strings.EqualFold(strings.TrimSpace(productsInput), "pick") {
+			picked, err := s.pickProducts(allProducts)
+			if err != nil {
+				fmt.Println(s.redText(err.Error()))
+				continue
 			}
-
-		case "macOSx64":
-			newProductsToAdd = map[string]string{
-				"R2023b": "Simulink_Fault_Analyzer Polyspace_Test",
-				"R2023a": "MATLAB_Test",
-				"R2022b": "Medical_Imaging_Toolbox Simscape_Battery",
-				"R2022a": "Bluetooth_Toolbox DSP_HDL_Toolbox Requirements_Toolbox Industrial_Communication_Toolbox",
-				"R2021b": "RF_PCB_Toolbox",
-				"R2021a": "Satellite_Communications_Toolbox DDS_Blockset",
-				"R2020b": "UAV_Toolbox Radar_Toolbox Lidar_Toolbox",
-				"R2020a": "Simulink_Compiler Motor_Control_Blockset MATLAB_Web_App_Server Wireless_HDL_Toolbox",
-				"R2019b": "ROS_Toolbox Simulink_PLC_Coder Navigation_Toolbox",
-				"R2019a": "System_Composer SerDes_Toolbox Reinforcement_Learning_Toolbox Audio_Toolbox Mixed-Signal_Blockset AUTOSAR_Blockset Polyspace_Bug_Finder_Server Polyspace_Code_Prover_Server Automated_Driving_Toolbox Computer_Vision_Toolbox",
-				"R2018b": "Communications_Toolbox Simscape_Electrical Sensor_Fusion_and_Tracking_Toolbox Deep_Learning_Toolbox 5G_Toolbox WLAN_Toolbox LTE_Toolbox",
-				"R2018a": "Predictive_Maintenance_Toolbox Vehicle_Dynamics_Blockset",
-				"R2017b": "Aerospace_Blockset Aerospace_Toolbox Antenna_Toolbox Bioinformatics_Toolbox Control_System_Toolbox Curve_Fitting_Toolbox DSP_System_Toolbox Database_Toolbox Datafeed_Toolbox Econometrics_Toolbox Embedded_Coder Financial_Instruments_Toolbox Financial_Toolbox Fixed-Point_Designer Fuzzy_Logic_Toolbox Global_Optimization_Toolbox HDL_Coder Image_Acquisition_Toolbox Image_Processing_Toolbox Instrument_Control_Toolbox MATLAB MATLAB_Coder MATLAB_Compiler MATLAB_Compiler_SDK MATLAB_Production_Server MATLAB_Report_Generator Mapping_Toolbox Model_Predictive_Control_Toolbox Network_License_Manager Optimization_Toolbox Parallel_Computing_Toolbox Partial_Differential_Equation_Toolbox Phased_Array_System_Toolbox Polyspace_Bug_Finder Polyspace_Code_Prover Powertrain_Blockset RF_Blockset RF_Toolbox Risk_Management_Toolbox Robotics_System_Toolbox Robust_Control_Toolbox Signal_Processing_Toolbox SimBiology SimEvents Simscape Simscape_Driveline Simscape_Fluids Simscape_Multibody Simulink Simulink_3D_Animation Simulink_Check Simulink_Coder Simulink_Control_Design Simulink_Coverage Simulink_Design_Optimization Simulink_Design_Verifier Simulink_Desktop_Real-Time Simulink_Report_Generator Simulink_Test Stateflow Statistics_and_Machine_Learning_Toolbox Symbolic_Math_Toolbox System_Identification_Toolbox Text_Analytics_Toolbox Wavelet_Toolbox",
+			if len(picked) == 0 {
+				fmt.Println(s.redText("No products were selected."))
+				continue
 			}
+			productsInput = strings.Join(picked, " ")
+		}
 
-		case "macOSARM":
-			newProductsToAdd = map[string]string{
-				"R2023b": "5G_Toolbox AUTOSAR_Blockset Aerospace_Blockset Aerospace_Toolbox Antenna_Toolbox Audio_Toolbox Automated_Driving_Toolbox Bioinformatics_Toolbox Bluetooth_Toolbox Communications_Toolbox Computer_Vision_Toolbox Control_System_Toolbox Curve_Fitting_Toolbox DDS_Blockset DSP_HDL_Toolbox DSP_System_Toolbox Database_Toolbox Datafeed_Toolbox Deep_Learning_Toolbox Econometrics_Toolbox Embedded_Coder Financial_Instruments_Toolbox Financial_Toolbox Fixed-Point_Designer Fuzzy_Logic_Toolbox Global_Optimization_Toolbox HDL_Coder Image_Acquisition_Toolbox Image_Processing_Toolbox Industrial_Communication_Toolbox Instrument_Control_Toolbox LTE_Toolbox Lidar_Toolbox MATLAB MATLAB_Coder MATLAB_Compiler MATLAB_Compiler_SDK MATLAB_Report_Generator MATLAB_Test Mapping_Toolbox Medical_Imaging_Toolbox Mixed-Signal_Blockset Model_Predictive_Control_Toolbox Motor_Control_Blockset Navigation_Toolbox Network_License_Manager Optimization_Toolbox Parallel_Computing_Toolbox Partial_Differential_Equation_Toolbox Phased_Array_System_Toolbox Powertrain_Blockset Predictive_Maintenance_Toolbox RF_Blockset RF_PCB_Toolbox RF_Toolbox ROS_Toolbox Radar_Toolbox Reinforcement_Learning_Toolbox Requirements_Toolbox Risk_Management_Toolbox Robotics_System_Toolbox Robust_Control_Toolbox Satellite_Communications_Toolbox Sensor_Fusion_and_Tracking_Toolbox SerDes_Toolbox Signal_Processing_Toolbox SimBiology SimEvents Simscape Simscape_Battery Simscape_Driveline Simscape_Electrical Simscape_Fluids Simscape_Multibody Simulink Simulink_3D_Animation Simulink_Check Simulink_Coder Simulink_Compiler Simulink_Control_Design Simulink_Coverage Simulink_Design_Optimization Simulink_Design_Verifier Simulink_Fault_Analyzer Simulink_PLC_Coder Simulink_Report_Generator Simulink_Test Stateflow Statistics_and_Machine_Learning_Toolbox Symbolic_Math_Toolbox System_Composer System_Identification_Toolbox Text_Analytics_Toolbox UAV_Toolbox Vehicle_Dynamics_Blockset WLAN_Toolbox Wavelet_Toolbox Wireless_HDL_Toolbox",
-			}
+		products, expansions, unresolved, leftOut := s.productsFromInput(strings.Fields(productsInput), allProducts)
+		s.printPatternExpansions(expansions)
+		s.printLeftOut(leftOut)
+		if len(unresolved) > 0 {
+			s.printUnresolvedProducts(unresolved)
+			fmt.Println(s.redText("Please try again. Different products should be separated by spaces. Spaces in a product name should be replaced with underscores."))
+			continue
+		}
+		products, err = s.withDependencies(products)
+		if err != nil {
+			fmt.Println(s.redText(err.Error()))
+			fmt.Println(s.redText("Please try again without the products above."))
+			continue
 		}
+		s.products = products
+		break
+	}
+	return nil
+}
 
-		// Use a loop to go through the list above to add the appropriate products.
-		selectedIdx := releaseIndex(s.release)
-		for releaseLoop, product := range newProductsToAdd {
-			if selectedIdx >= releaseIndex(releaseLoop) {
-				allProducts = append(allProducts, strings.Fields(product)...)
-			}
+// productsFromInput determines the products we'll actually be using with MPM. No input or "all" selects every product.
+// Bundle names can be mixed with product names, and a product that's asked for more than once is only installed once.
+// Anything with a "-" in front of it is left out, so "all -Polyspace_Test" installs everything except Polyspace Test.
+// Wildcard patterns such as "Simscape*" are expanded, and each expansion is returned so it can be shown. Products that
+// require a product that was left out are left out too, and returned so that the user can be told.
+func (s *mpmSession) productsFromInput(inputProducts, allProducts []string) ([]string, []patternExpansion, []productSuggestion, []dependencyConflict) {
+	var include, exclude []string
+	everything := false
+	for _, input := range inputProducts {
+		if name, found := strings.CutPrefix(input, "-"); found && name != "" {
+			exclude = append(exclude, name)
+		} else if strings.EqualFold(input, "all") {
+			everything = true
+		} else {
+			include = append(include, input)
 		}
+	}
 
-		// Old products to add.
-		switch s.platform {
-		case "windows":
-			oldProductsToAdd = map[string]string{
-				"R2024b": "Filter_Design_HDL_Coder",
-				"R2021b": "Simulink_Requirements OPC_Toolbox",
-				"R2020b": "Trading_Toolbox",
-				"R2019b": "LTE_HDL_Toolbox",
-				"R2018b": "Audio_System_Toolbox Automated_Driving_System_Toolbox Computer_Vision_System_Toolbox MATLAB_Distributed_Computing_Server",
-				"R2018a": "Communications_System_Toolbox LTE_System_Toolbox Neural_Network_Toolbox Simscape_Electronics Simscape_Power_Systems WLAN_System_Toolbox",
-			}
+	// Only what was typed decides whether everything is installed. Bundles and patterns that turn out to hold
+	// nothing are reported below, rather than selecting every product.
+	everything = everything || len(include) == 0
+	include, unresolved := s.expandBundles(include)
+	include, expansions, unresolvedPatterns := expandPatterns(include, allProducts)
+	resolved, unresolvedIncludes := resolveProducts(include, allProducts)
+	s.explainUnavailable(unresolvedIncludes)
+	unresolved = append(append(unresolved, unresolvedPatterns...), unresolvedIncludes...)
+	if everything {
+		resolved = allProducts
+	}
 
-		case "linux":
-			oldProductsToAdd = map[string]string{
-				"R2024b": "Filter_Design_HDL_Coder",
-				"R2021b": "Simulink_Requirements",
-				"R2020b": "Trading_Toolbox",
-				"R2019b": "LTE_HDL_Toolbox",
-				"R2018b": "Audio_System_Toolbox Automated_Driving_System_Toolbox Computer_Vision_System_Toolbox MATLAB_Distributed_Computing_Server",
-				"R2018a": "Communications_System_Toolbox LTE_System_Toolbox Neural_Network_Toolbox Simscape_Electronics Simscape_Power_Systems WLAN_System_Toolbox",
-			}
+	// Excluded products get checked the same way, so a typo isn't silently ignored. Leaving out a bundle that has
+	// nothing for this release leaves out nothing, which is fine.
+	exclude, _ = s.expandBundles(exclude)
+	exclude, excludedExpansions, unresolvedExclusions := expandPatterns(exclude, allProducts)
+	excluded, unresolvedExcludedNames := resolveProducts(exclude, allProducts)
+	s.explainUnavailable(unresolvedExcludedNames)
+	for _, e := range excludedExpansions {
+		e.pattern = "-" + e.pattern
+		expansions = append(expansions, e)
+	}
+	for _, u := range append(unresolvedExclusions, unresolvedExcludedNames...) {
+		u.input = "-" + u.input
+		unresolved = append(unresolved, u)
+	}
 
-		case "macOSx64":
-			oldProductsToAdd = map[string]string{
-				"R2024b": "Filter_Design_HDL_Coder",
-				"R2021b": "Simulink_Requirements MATLAB_Parallel_Server",
-				"R2020b": "Trading_Toolbox",
-				"R2019b": "LTE_HDL_Toolbox",
-				"R2018b": "Audio_System_Toolbox Automated_Driving_System_Toolbox Computer_Vision_System_Toolbox MATLAB_Distributed_Computing_Server",
-				"R2018a": "Communications_System_Toolbox LTE_System_Toolbox Neural_Network_Toolbox Simscape_Electronics Simscape_Power_Systems WLAN_System_Toolbox",
-			}
-		case "macOSARM":
-			oldProductsToAdd = map[string]string{
-				"R2024b": "Filter_Design_HDL_Coder",
-			}
+	var products []string
+	var leftOut []dependencyConflict
+	for _, p := range resolved {
+		if slices.Contains(products, p) || slices.Contains(excluded, p) {
+			continue
 		}
+		// Otherwise the excluded product would just be added back as a dependency.
+		_, added, _ := catalog.resolveDependencies(s.platform, s.release, []string{p})
+		if i := slices.IndexFunc(added, func(a addedDependency) bool { return slices.Contains(excluded, a.product) }); i >= 0 {
+			leftOut = append(leftOut, dependencyConflict{product: p, requires: added[i].product})
+			continue
+		}
+		products = append(products, p)
+	}
 
-		// The actual for loop that goes through the list above. Note that it uses the same logic as newProducts, it just uses <= instead of >=.
-		for releaseLoop, product := range oldProductsToAdd {
-			if selectedIdx <= releaseIndex(releaseLoop) {
-				allProducts = append(allProducts, strings.Fields(product)...)
-			}
+	if len(products) == 0 && len(unresolved) == 0 {
+		unresolved = append(unresolved, productSuggestion{
+			input:   strings.Join(inputProducts, " "),
+			problem: "nothing is left to install once the excluded products are left out",
+		})
+	}
+	return products, expansions, unresolved, leftOut
+}
+
+func (s *mpmSession) printPatternExpansions(expansions []patternExpansion) {
+	for _, e := range expansions {
+		fmt.Printf("%s matches: %s\n", e.pattern, s.greenText(strings.Join(e.products, " ")))
+	}
+}
+
+// printLeftOut tells the user about products that were left out because they need an excluded product.
+func (s *mpmSession) printLeftOut(leftOut []dependencyConflict) {
+	var excluded []string
+	byExcluded := make(map[string][]string)
+	for _, l := range leftOut {
+		if _, seen := byExcluded[l.requires]; !seen {
+			excluded = append(excluded, l.requires)
 		}
+		byExcluded[l.requires] = append(byExcluded[l.requires], l.product)
+	}
+	for _, e := range excluded {
+		fmt.Printf("Leaving out these products too, since they require %s: %s\n", e, s.redText(strings.Join(byExcluded[e], " ")))
+	}
+}
 
-		// Determine the products we'll actually be using with MPM.
-		if productsInput == "" {
-			s.products = allProducts
-		} else if strings.EqualFold(productsInput, "parallel_products") {
-			if selectedIdx <= releaseIndex("R2018b") {
-				s.products = []string{"MATLAB", "Parallel_Computing_Toolbox", "MATLAB_Distributed_Computing_Server"}
-			} else {
-				s.products = []string{"MATLAB", "Parallel_Computing_Toolbox", "MATLAB_Parallel_Server"}
-			}
+func (s *mpmSession) printUnresolvedProducts(unresolved []productSuggestion) {
+	fmt.Println(s.redText("The following products can't be installed:"))
+	for _, u := range unresolved {
+		line := "- " + u.input
+		if u.problem != "" {
+			line = "- " + u.problem
+		}
+		if u.suggestion != "" {
+			fmt.Printf("  %s  did you mean %s?\n", s.redText(line), s.greenText(u.suggestion))
 		} else {
-			inputProducts := strings.Fields(productsInput)
-			resolved, unresolved := resolveProducts(inputProducts, allProducts)
-			if len(unresolved) > 0 {
-				fmt.Println(s.redText("The following products were not recognized:"))
-				for _, u := range unresolved {
-					if u.suggestion != "" {
-						fmt.Printf("  %s  did you mean %s?\n", s.redText("- "+u.input), s.greenText(u.suggestion))
-					} else {
-						fmt.Println(s.redText("- " + u.input))
-					}
-				}
-				fmt.Println(s.redText("Please try again. Different products should be separated by spaces. Spaces in a product name should be replaced with underscores."))
-				continue
-			}
-			s.products = resolved
+			fmt.Println(s.redText(line))
 		}
-		break
 	}
-	return nil
 }
 
 // Select the installation path.
-func (s *mpmSession) selectInstallPath() error {
-	// Set the default installation path based on your OS.
-	var defaultInstallationPath string
+// defaultInstallPath is where MathWorks installs the selected release by default on your OS.
+func (s *mpmSession) defaultInstallPath() string {
 	switch {
 	case s.platform == "macOSx64" || s.platform == "macOSARM":
-		defaultInstallationPath = "/Applications/MATLAB_" + s.release + ".app"
+		return "/Applications/MATLAB_" + s.release + ".app"
 	case s.platform == "windows":
-		defaultInstallationPath = "C:\\Program Files\\MATLAB\\" + s.release
+		return "C:\\Program Files\\MATLAB\\" + s.release
 	case s.platform == "linux":
-		defaultInstallationPath = "/usr/local/MATLAB/" + s.release
+		return "/usr/local/MATLAB/" + s.release
+	}
+	return ""
+}
+
+// isDefaultInstallPath reports whether path is the default installation path for any release.
+func (s *mpmSession) isDefaultInstallPath(path string) bool {
+	for _, release := range catalog.Releases {
+		if path == (&mpmSession{platform: s.platform, release: release}).defaultInstallPath() {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *mpmSession) selectInstallPath() error {
+	defaultInstallationPath := s.defaultInstallPath()
+	// After going back, a path that was typed in is the default. One that was the default for another release isn't.
+	if s.installPath != "" && !s.isDefaultInstallPath(s.installPath) {
+		defaultInstallationPath = s.installPath
+	}
+
+	if s.nonInteractive {
+		if s.dryRun {
+			fmt.Printf("Dry run: the products would be installed to %s.\n", dryRunDirectory(s.installPath))
+			return nil
+		}
+		if err := os.MkdirAll(s.installPath, 0755); err != nil {
+			return fmt.Errorf("error creating installation directory: %w", err)
+		}
+		return nil
 	}
 
+	s.completeFiles()
 	for {
 		fmt.Print("Enter the full path where you would like to install these products. "+
 			"Press Enter to install to default path: \"", defaultInstallationPath, "\"\n> ")
 
 		installPath, err := readUserInput(s.rl)
-		if err != nil {
+		if err == errGoBack {
+			return err
+		} else if err != nil {
 			if err.Error() == "Interrupt" {
 				fmt.Println(s.redText("Exiting from user input."))
 			} else {
@@ -613,6 +887,8 @@ func (s *mpmSession) selectInstallPath() error {
 
 		if installPath == "" {
 			installPath = defaultInstallationPath
+		} else if s.dryRun {
+			fmt.Printf("Dry run: the products would be installed to %s.\n", dryRunDirectory(installPath))
 		} else {
 			if _, err := os.Stat(installPath); os.IsNotExist(err) {
 				if err := os.MkdirAll(installPath, 0755); err != nil {
@@ -642,12 +918,22 @@ func (s *mpmSession) selectInstallPath() error {
 
 // Optional license file selection.
 func (s *mpmSession) selectLicenseFile() error {
+	if s.nonInteractive {
+		if !s.licenseUsed {
+			return nil
+		}
+		return checkLicenseFile(s.licensePath)
+	}
+
+	s.completeFiles()
 	for {
 		fmt.Print("If you have a license file you'd like to include in your installation, " +
 			"please provide the full path to the existing license file.\n> ")
 
 		licensePath, err := readUserInput(s.rl)
-		if err != nil {
+		if err == errGoBack {
+			return err
+		} else if err != nil {
 			if err.Error() == "Interrupt" {
 				fmt.Println(s.redText("Exiting from user input."))
 			} else {
@@ -662,33 +948,32 @@ func (s *mpmSession) selectLicenseFile() error {
 			s.licenseUsed = false
 			break
 		} else {
-			// Check if the license file exists and has the correct extension.
-			_, err := os.Stat(licensePath)
-			if err != nil {
+			if err := checkLicenseFile(licensePath); err != nil {
 				fmt.Println(s.redText("Error: ", err))
 				continue
-			} else if !strings.HasSuffix(licensePath, ".dat") && !strings.HasSuffix(licensePath, ".lic") && !strings.HasSuffix(licensePath, ".xml") {
-				fmt.Println(s.redText("Invalid file extension. Please provide a file with a .dat, .lic, or .xml file extension."))
-				continue
-			} else {
-				s.licenseUsed = true
-				s.licensePath = licensePath
-				break
 			}
+			s.licenseUsed = true
+			s.licensePath = licensePath
+			break
 		}
 	}
 	return nil
 }
 
+// Check if the license file exists and has the correct extension.
+func checkLicenseFile(licensePath string) error {
+	if _, err := os.Stat(licensePath); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(licensePath, ".dat") && !strings.HasSuffix(licensePath, ".lic") && !strings.HasSuffix(licensePath, ".xml") {
+		return fmt.Errorf("invalid file extension. Please provide a file with a .dat, .lic, or .xml file extension")
+	}
+	return nil
+}
+
 // Construct the command and run MPM.
 func (s *mpmSession) runMPM() error {
-	fmt.Println("Loading, please wait.")
-
-	mpmBinary := "mpm"
-	if s.platform == "windows" {
-		mpmBinary = "mpm.exe"
-	}
-	s.mpmFullPath = filepath.Join(s.mpmDownloadPath, mpmBinary)
+	s.mpmFullPath = filepath.Join(s.mpmDownloadPath, s.mpmBinaryName())
 
 	cmdArgs := []string{
 		s.mpmFullPath,
@@ -699,14 +984,47 @@ func (s *mpmSession) runMPM() error {
 	}
 	cmdArgs = append(cmdArgs, s.products...)
 
+	// Long product lists are given to MPM in an input file, so the command line stays within the platform's limits.
+	useInputFile := commandLineLength(cmdArgs) > maxCommandLineLength[s.platform]
+
+	if s.dryRun {
+		s.printDryRun(cmdArgs)
+		if useInputFile {
+			fmt.Println("The product list is too long for the command line, so a real run would instead write the release, destination and products " +
+				"to a temporary MPM input file and run MPM with \"install --inputfile=<that file>\".")
+		}
+		return nil
+	}
+	if useInputFile {
+		inputPath, err := s.temporaryInputFile()
+		if err != nil {
+			return fmt.Errorf("error writing a temporary MPM input file for the products: %w", err)
+		}
+		defer os.Remove(inputPath)
+		// The file holds the release and destinationFolder as well, like MathWorks' templates, which are meant to be
+		// installed with nothing else on the command line.
+		cmdArgs = []string{s.mpmFullPath, "install", "--inputfile=" + inputPath}
+	}
+	fmt.Println("Loading, please wait.")
+
 	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Env = append(os.Environ(), s.network.environment()...)
 
 	// Use customWriter to intercept and process MPM's output.
 	cmd.Stdout = &customWriter{writer: os.Stdout}
 	cmd.Stderr = &customWriter{writer: os.Stderr}
-	err := cmd.Run() // Run it already geeeeeeeez.
+	err := s.mpm.start(cmd) // Run it already geeeeeeeez.
+	if err == nil {
+		err = cmd.Wait()
+		if interrupted := s.mpm.finish(); interrupted != nil {
+			return interrupted
+		}
+	}
 
 	if err != nil {
+		if s.nonInteractive {
+			return fmt.Errorf("an error occurred during installation. See the error above for more information: %w", err)
+		}
 		errString := err.Error()
 		if strings.Contains(errString, "mpm: no such file or directory") || strings.Contains(errString, "mpm.exe: no such file or directory") {
 			fmt.Println(s.redText("MPM was either moved, renamed, deleted, or you've lost permissions to access it. Press the Enter/Return key to close this program."))
@@ -723,6 +1041,10 @@ func (s *mpmSession) installLicenseFile() error {
 	if !s.licenseUsed {
 		return nil
 	}
+	if s.dryRun {
+		fmt.Printf("Dry run: %s would be copied to %s.\n", s.licensePath, filepath.Join(s.installPath, "licenses"))
+		return nil
+	}
 
 	// Create the licenses directory.
 	licensesDir := filepath.Join(s.installPath, "licenses")
@@ -785,30 +1107,21 @@ func hasAdminRights() (bool, error) {
 	return true, nil
 }
 
-func downloadFile(url string, filePath string) error {
-	response, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed: HTTP %d %s", response.StatusCode, response.Status)
-	}
-
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	_, err = io.Copy(file, response.Body)
-	return err
-}
-
 type productSuggestion struct {
 	input      string
 	suggestion string
+	problem    string // Why the input can't be used, if it's more than not being recognized.
+}
+
+func (u productSuggestion) message() string {
+	message := fmt.Sprintf("product %q was not recognized", u.input)
+	if u.problem != "" {
+		message = u.problem
+	}
+	if u.suggestion != "" {
+		message += ". Did you mean " + u.suggestion + "?"
+	}
+	return message
 }
 
 // resolveProducts matches input product names against available products using
@@ -832,6 +1145,44 @@ func resolveProducts(inputProducts, availableProducts []string) (resolved []stri
 	return
 }
 
+type patternExpansion struct {
+	pattern  string
+	products []string
+}
+
+// expandPatterns replaces wildcard patterns (*, ? and [...]) with every available product they match, ignoring case.
+// Anything that isn't a pattern is passed through untouched. A pattern that matches nothing is unresolved, with a
+// suggestion based on the pattern's text.
+func expandPatterns(inputProducts, availableProducts []string) (expanded []string, expansions []patternExpansion, unresolved []productSuggestion) {
+	for _, input := range inputProducts {
+		if !strings.ContainsAny(input, "*?[") {
+			expanded = append(expanded, input)
+			continue
+		}
+
+		var matches []string
+		for _, p := range availableProducts {
+			if matched, _ := path.Match(strings.ToLower(input), strings.ToLower(p)); matched {
+				matches = append(matches, p)
+			}
+		}
+		if len(matches) == 0 {
+			literal := strings.Map(func(r rune) rune {
+				if strings.ContainsRune("*?[]", r) {
+					return -1
+				}
+				return r
+			}, input)
+			suggestion := closestProduct(literal, availableProducts)
+			unresolved = append(unresolved, productSuggestion{input: input, suggestion: suggestion})
+			continue
+		}
+		expanded = append(expanded, matches...)
+		expansions = append(expansions, patternExpansion{pattern: input, products: matches})
+	}
+	return
+}
+
 // closestProduct finds the available product with the smallest edit distance
 // to input, returning it only if the distance is reasonable.
 func closestProduct(input string, products []string) string {
@@ -883,7 +1234,11 @@ func levenshtein(a, b string) int {
 	return prev[lb]
 }
 
-// Reading user input in a separate function allows me to accept input such as "quit" or "exit" without needing to repeat said code.
+// errGoBack is returned by readUserInput when the user types "back". Steps return it as-is so that main can take
+// them back to the previous question.
+var errGoBack = errors.New("going back to the previous question")
+
+// Reading user input in a separate function allows me to accept input such as "quit", "exit" or "back" without needing to repeat said code.
 func readUserInput(rl *readline.Instance) (string, error) {
 	redText := color.New(color.FgRed).SprintFunc()
 	line, err := rl.Readline()
@@ -900,6 +1255,9 @@ func readUserInput(rl *readline.Instance) (string, error) {
 		fmt.Println(redText("\nExiting from user input."))
 		os.Exit(0)
 	}
+	if lineLower == "back" {
+		return "", errGoBack
+	}
 	return line, nil
 }
 
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProductsFromInput(t *testing.T) {
+	s := &mpmSession{
+		platform: "linux",
+		release:  "R2024b",
+		bundles: map[string]productBundle{
+			"controls": {{Product: "Control_System_Toolbox"}, {Product: "Simulink_Control_Design"}},
+			"hdl_new":  {{Product: "DSP_HDL_Toolbox", From: "R2025a"}},
+		},
+	}
+	allProducts := catalog.productsFor(s.platform, s.release)
+	withoutSimulink := slices.DeleteFunc(slices.Clone(allProducts), func(p string) bool {
+		_, added, _ := catalog.resolveDependencies(s.platform, s.release, []string{p})
+		return p == "Simulink" || slices.ContainsFunc(added, func(a addedDependency) bool { return a.product == "Simulink" })
+	})
+	simscape := slices.DeleteFunc(slices.Clone(allProducts), func(p string) bool { return !strings.HasPrefix(p, "Simscape") })
+
+	tests := []struct {
+		name       string
+		input      string
+		want       []string
+		unresolved []string // Messages for what can't be installed.
+		leftOut    []string // Products left out for needing an excluded one.
+	}{
+		{name: "nothing typed", input: "", want: allProducts},
+		{name: "all", input: "all", want: allProducts},
+		{name: "names ignore case", input: "matlab SIMULINK", want: []string{"MATLAB", "Simulink"}},
+		{name: "repeated product", input: "MATLAB MATLAB", want: []string{"MATLAB"}},
+		{name: "exclude from all", input: "all -Simulink", want: withoutSimulink, leftOut: []string{"Aerospace_Blockset"}},
+		{name: "exclusion alone means all", input: "-Simulink", want: withoutSimulink, leftOut: []string{"Aerospace_Blockset"}},
+		{name: "exclude a dependency", input: "MATLAB Aerospace_Blockset -Simulink", want: []string{"MATLAB"}, leftOut: []string{"Aerospace_Blockset"}},
+		{name: "pattern", input: "Simscape*", want: simscape},
+		{name: "pattern with exclusion", input: "simscape* -Simscape_Battery", want: slices.DeleteFunc(slices.Clone(simscape), func(p string) bool { return p == "Simscape_Battery" })},
+		{name: "pattern that matches nothing", input: "Nothing_Like_This*", unresolved: []string{`product "Nothing_Like_This*" was not recognized`}},
+		{name: "bundle", input: "controls MATLAB", want: []string{"Control_System_Toolbox", "Simulink_Control_Design", "MATLAB"}},
+		{name: "excluded bundle", input: "Control_System_Toolbox MATLAB -controls", want: []string{"MATLAB"}},
+		{name: "bundle with nothing for the release", input: "hdl_new", unresolved: []string{"bundle hdl_new has no products for R2024b"}},
+		{name: "excluded bundle with nothing for the release", input: "MATLAB -hdl_new", want: []string{"MATLAB"}},
+		{name: "typo", input: "Simulnk", unresolved: []string{`product "Simulnk" was not recognized. Did you mean Simulink?`}},
+		{name: "excluded typo", input: "all -Simulnk", want: allProducts, unresolved: []string{`product "-Simulnk" was not recognized. Did you mean Simulink?`}},
+		{name: "not on this platform", input: "Data_Acquisition_Toolbox", unresolved: []string{"Data_Acquisition_Toolbox isn't available for R2024b on linux"}},
+		{name: "renamed", input: "Neural_Network_Toolbox", unresolved: []string{"Neural_Network_Toolbox isn't available for R2024b on linux. Did you mean Deep_Learning_Toolbox?"}},
+		{name: "everything excluded", input: "Simulink -Simulink", unresolved: []string{"nothing is left to install once the excluded products are left out"}},
+		{name: "pattern excluded by itself", input: "Polyspace_* -Polyspace_*", unresolved: []string{"nothing is left to install once the excluded products are left out"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products, _, unresolved, leftOut := s.productsFromInput(strings.Fields(tt.input), allProducts)
+
+			if !slices.Equal(products, tt.want) {
+				t.Errorf("products = %v, want %v", products, tt.want)
+			}
+
+			var messages []string
+			for _, u := range unresolved {
+				messages = append(messages, u.message())
+			}
+			if !slices.Equal(messages, tt.unresolved) {
+				t.Errorf("unresolved = %q, want %q", messages, tt.unresolved)
+			}
+
+			if tt.leftOut != nil {
+				for _, want := range tt.leftOut {
+					if !slices.ContainsFunc(leftOut, func(l dependencyConflict) bool { return l.product == want }) {
+						t.Errorf("%s wasn't reported as left out: %v", want, leftOut)
+					}
+				}
+			} else if len(leftOut) > 0 {
+				t.Errorf("left out %v, want nothing", leftOut)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	readline "github.com/Jestzer/readlineJestzer"
+)
+
+var errPickerCancelled = errors.New("product selection was cancelled")
+
+// The order families are shown in. Everything that isn't in another family is a MATLAB toolbox.
+var productFamilies = []string{"MATLAB", "Simulink", "Coders", "Blocksets", "Polyspace", "Servers and Deployment"}
+
+// productFamily sorts a product into one of productFamilies based on its name.
+func productFamily(name string) string {
+	switch {
+	case strings.HasPrefix(name, "Polyspace_"):
+		return "Polyspace"
+	case strings.Contains(name, "Coder"):
+		return "Coders"
+	case strings.HasSuffix(name, "_Blockset"):
+		return "Blocksets"
+	case strings.HasPrefix(name, "Simulink"), strings.HasPrefix(name, "Simscape"),
+		slices.Contains([]string{"Stateflow", "SimEvents", "System_Composer", "Requirements_Toolbox"}, name):
+		return "Simulink"
+	case strings.HasSuffix(name, "_Server"),
+		slices.Contains([]string{"MATLAB_Compiler", "MATLAB_Compiler_SDK", "Network_License_Manager"}, name):
+		return "Servers and Deployment"
+	default:
+		return "MATLAB"
+	}
+}
+
+// productPicker is a checkbox list of products, grouped by family, that can be filtered by typing.
+type productPicker struct {
+	products  []string // Grouped by family.
+	families  []string // The family of each entry in products.
+	selected  map[string]bool
+	filter    string
+	filtering bool
+	cursor    int // Index into visible().
+}
+
+func newProductPicker(allProducts, preselected []string) *productPicker {
+	p := &productPicker{selected: make(map[string]bool)}
+	for _, family := range productFamilies {
+		for _, product := range allProducts {
+			if productFamily(product) == family {
+				p.products = append(p.products, product)
+				p.families = append(p.families, family)
+			}
+		}
+	}
+	for _, product := range preselected {
+		p.selected[product] = true
+	}
+	return p
+}
+
+// visible lists the indexes of the products that match the filter.
+func (p *productPicker) visible() []int {
+	var indexes []int
+	filter := strings.ToLower(p.filter)
+	for i, product := range p.products {
+		if strings.Contains(strings.ToLower(product), filter) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// handleKey updates the picker for one key press. It returns true once the user is done.
+func (p *productPicker) handleKey(key []byte) (done bool, err error) {
+	visible := p.visible()
+
+	if p.filtering {
+		switch {
+		case len(key) == 1 && (key[0] == '\r' || key[0] == '\n' || key[0] == 0x1b):
+			p.filtering = false
+		case len(key) == 1 && (key[0] == 0x7f || key[0] == 0x08):
+			if p.filter != "" {
+				p.filter = p.filter[:len(p.filter)-1]
+			}
+		case len(key) == 1 && key[0] == 0x03:
+			return true, errPickerCancelled
+		case len(key) == 1 && key[0] >= ' ' && key[0] < 0x7f:
+			p.filter += string(key)
+		}
+		p.cursor = min(p.cursor, max(len(p.visible())-1, 0))
+		return false, nil
+	}
+
+	switch string(key) {
+	case "\x1b[A", "\x1bOA", "k":
+		p.cursor = max(p.cursor-1, 0)
+	case "\x1b[B", "\x1bOB", "j":
+		p.cursor = min(p.cursor+1, max(len(visible)-1, 0))
+	case " ":
+		if len(visible) > 0 {
+			product := p.products[visible[p.cursor]]
+			p.selected[product] = !p.selected[product]
+		}
+	case "a", "n":
+		for _, i := range visible {
+			p.selected[p.products[i]] = string(key) == "a"
+		}
+	case "/":
+		p.filtering = true
+	case "\x1b":
+		p.filter = ""
+		p.cursor = 0
+	case "\r", "\n":
+		return true, nil
+	case "q", "\x03":
+		return true, errPickerCancelled
+	}
+	return false, nil
+}
+
+// choices returns the selected products in the order they're listed.
+func (p *productPicker) choices() []string {
+	var chosen []string
+	for _, product := range p.products {
+		if p.selected[product] {
+			chosen = append(chosen, product)
+		}
+	}
+	return chosen
+}
+
+// render draws the picker so that the cursor is always on screen.
+func (p *productPicker) render(height int) string {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	b.WriteString("Up/Down: move  Space: select  a/n: select all/none shown  /: filter  Esc: clear filter  Enter: done  q: cancel\r\n")
+	if p.filtering {
+		fmt.Fprintf(&b, "Filter: %s_\r\n", p.filter)
+	} else {
+		fmt.Fprintf(&b, "Filter: %s    (%d selected)\r\n", p.filter, len(p.choices()))
+	}
+
+	// Lay out family headings and products, then only draw the part of it that fits.
+	var lines []string
+	cursorLine := 0
+	lastFamily := ""
+	for n, i := range p.visible() {
+		if p.families[i] != lastFamily {
+			lastFamily = p.families[i]
+			lines = append(lines, "\033[1m"+lastFamily+"\033[0m")
+		}
+		box := "[ ]"
+		if p.selected[p.products[i]] {
+			box = "[x]"
+		}
+		line := "  " + box + " " + p.products[i]
+		if n == p.cursor {
+			cursorLine = len(lines)
+			line = "\033[7m" + line + "\033[0m"
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "No products match the filter.")
+	}
+
+	rows := max(height-3, 1)
+	start := 0
+	if cursorLine >= rows {
+		start = cursorLine - rows + 1
+	}
+	end := min(start+rows, len(lines))
+	for _, line := range lines[start:end] {
+		b.WriteString(line + "\r\n")
+	}
+	return b.String()
+}
+
+// pickProducts shows the product picker in the terminal and returns the products that were checked.
+func (s *mpmSession) pickProducts(allProducts []string) ([]string, error) {
+	fd := readline.GetStdin()
+	if !readline.IsTerminal(fd) {
+		return nil, errors.New("the product picker needs an interactive terminal")
+	}
+	state, err := readline.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		readline.Restore(fd, state)
+		fmt.Print("\033[H\033[2J")
+	}()
+
+	p := newProductPicker(allProducts, s.products)
+	input := make([]byte, 64)
+	for {
+		_, height, err := readline.GetSize(fd)
+		if err != nil || height <= 0 {
+			height = 24
+		}
+		fmt.Print(p.render(height))
+
+		n, err := os.Stdin.Read(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range splitKeys(input[:n]) {
+			done, err := p.handleKey(key)
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				return p.choices(), nil
+			}
+		}
+	}
+}
+
+// splitKeys separates what was read from the terminal into key presses, since typing quickly or pasting can
+// deliver several at once. Arrow keys arrive as three-byte escape sequences.
+func splitKeys(input []byte) [][]byte {
+	var keys [][]byte
+	for len(input) > 0 {
+		size := 1
+		if input[0] == 0x1b && len(input) >= 3 && (input[1] == '[' || input[1] == 'O') {
+			size = 3
+		}
+		keys = append(keys, input[:size])
+		input = input[size:]
+	}
+	return keys
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// mpmProcess keeps track of MPM while it runs, so that Ctrl+C stops it too rather than leaving it running on its own.
+type mpmProcess struct {
+	mu          sync.Mutex
+	cmd         *exec.Cmd     // nil when MPM isn't running.
+	stoppedBy   os.Signal     // The signal that MPM was asked to stop with, if any.
+	gracePeriod time.Duration // How long MPM has to stop before it's killed.
+}
+
+// interruptedError is returned when MPM was stopped by a signal.
+type interruptedError struct {
+	signal os.Signal
+}
+
+func (e *interruptedError) Error() string {
+	return fmt.Sprintf("MPM was stopped (%v) before it finished, so the installation is incomplete. Run this program again with --resume to try again", e.signal)
+}
+
+// exitCode follows the shell's convention of 128 plus the signal number, so that scripts can tell an interrupted
+// installation from a failed one.
+func (e *interruptedError) exitCode() int {
+	if sig, ok := e.signal.(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+	return 130
+}
+
+// start starts MPM in its own process group and keeps track of it until finish is called.
+func (p *mpmProcess) start(cmd *exec.Cmd) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	p.cmd = cmd
+	p.stoppedBy = nil
+	return nil
+}
+
+// finish stops keeping track of MPM once it has exited. It returns an error if MPM was stopped by a signal.
+func (p *mpmProcess) finish() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cmd = nil
+	if p.stoppedBy != nil {
+		return &interruptedError{p.stoppedBy}
+	}
+	return nil
+}
+
+// stop passes sig on to MPM and kills it if it hasn't exited within the grace period. A second signal kills it
+// straight away. It returns false if MPM isn't running.
+func (p *mpmProcess) stop(sig os.Signal, redText func(a ...any) string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cmd := p.cmd
+	if cmd == nil {
+		return false
+	}
+	if p.stoppedBy != nil {
+		fmt.Println(redText("\nKilling MPM."))
+		killProcessGroup(cmd)
+		return true
+	}
+
+	p.stoppedBy = sig
+	fmt.Println(redText(fmt.Sprintf("\nStopping MPM. It will be killed if it hasn't stopped within %v. Press Ctrl+C again to kill it now.", p.gracePeriod)))
+	if err := signalProcessGroup(cmd, sig); err != nil {
+		killProcessGroup(cmd)
+		return true
+	}
+	time.AfterFunc(p.gracePeriod, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.cmd == cmd {
+			fmt.Println(redText(fmt.Sprintf("MPM didn't stop within %v, so it was killed.", p.gracePeriod)))
+			killProcessGroup(cmd)
+		}
+	})
+	return true
+}
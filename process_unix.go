@@ -0,0 +1,27 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup puts MPM and anything it starts in a process group of their own, so that they can be signalled
+// together, and only once, instead of also receiving Ctrl+C from the terminal directly.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) error {
+	unixSignal, ok := sig.(syscall.Signal)
+	if !ok {
+		unixSignal = syscall.SIGTERM
+	}
+	return syscall.Kill(-cmd.Process.Pid, unixSignal)
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
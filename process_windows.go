@@ -0,0 +1,24 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// On Windows, MPM shares the console, which already gives it Ctrl+C. There's no process group to set up.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// Windows can't send signals to other processes. Ctrl+C has already reached MPM through the console, so MPM is only
+// left its grace period. Anything else, such as the console being closed, doesn't wait.
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) error {
+	if sig == os.Interrupt {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Apple Silicon Macs can install either the Intel or the ARM version of the products, so it's one of the answers.
+var canChooseArch = runtime.GOOS == "darwin" && runtime.GOARCH == "arm64"
+
+// archName describes which version of the products the platform is, for the summary.
+func archName(platform string) string {
+	if platform == "macOSARM" {
+		return "ARM"
+	}
+	return "Intel"
+}
+
+// printSummary shows everything MPM is about to be run with. The numbered fields can be changed from the review.
+func (s *mpmSession) printSummary() {
+	mpmPath := filepath.Join(s.mpmDownloadPath, s.mpmBinaryName())
+	mpmDetails := "not downloaded in a dry run"
+	if platforms, description, err := executablePlatforms(mpmPath); err == nil {
+		mpmDetails = description
+		if len(platforms) == 0 {
+			mpmDetails += ", which can't run here"
+		}
+	} else if !s.dryRun {
+		mpmDetails = err.Error()
+	}
+
+	license := "none"
+	if s.licenseUsed {
+		license = s.licensePath
+	}
+
+	fmt.Println("\nReady to install:")
+	fmt.Printf("   Platform:     %s\n", s.platform)
+	fmt.Printf("   MPM:          %s (%s)\n", mpmPath, mpmDetails)
+	fmt.Printf("1. Release:      %s\n", s.release)
+	fmt.Printf("2. Products:     %d\n", len(s.products))
+	fmt.Printf("                 %s\n", strings.Join(s.products, ", "))
+	fmt.Printf("3. Destination:  %s\n", s.installPath)
+	fmt.Printf("4. License file: %s\n", license)
+	if canChooseArch {
+		fmt.Printf("5. Version:      %s\n", archName(s.platform))
+	}
+	fmt.Println()
+}
+
+// reviewSelections shows a summary before MPM is run, and lets the user change any numbered field, cancel, or go
+// ahead. Without prompts, the summary is only printed.
+func (s *mpmSession) reviewSelections() error {
+	if s.nonInteractive {
+		s.printSummary()
+		return nil
+	}
+
+	fields := []string{"1", "2", "3", "4"}
+	if canChooseArch {
+		fields = append(fields, "5")
+	}
+	invalidChoice := fmt.Sprintf("Invalid choice. Please enter \"y\", \"n\", or a number from 1 to %d.", len(fields))
+
+	for {
+		s.printSummary()
+		s.completeWords(func() []string { return append([]string{"yes", "no"}, fields...) })
+		fmt.Print("Start the installation? Type \"y\" to start, \"n\" to cancel, or the number of a field to change it.\n> ")
+		answer, err := readUserInput(s.rl)
+		if err == errGoBack {
+			return err
+		} else if err != nil {
+			if err.Error() == "Interrupt" {
+				fmt.Println(s.redText("Exiting from user input."))
+			} else {
+				fmt.Println(s.redText("Error reading line: ", err))
+				continue
+			}
+			return err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes", "t", "true":
+			return nil
+		case "n", "no", "f", "false":
+			fmt.Println(s.redText("Installation cancelled. Press the Enter/Return key to close this program."))
+			ExitHelper(s.rl)
+		// Typing "back" while changing a field returns to the summary.
+		case "1":
+			if err := s.changeRelease(); err != nil && err != errGoBack {
+				return err
+			}
+		case "2":
+			if err := s.selectProducts(); err != nil && err != errGoBack {
+				return err
+			}
+		case "3":
+			if err := s.selectInstallPath(); err != nil && err != errGoBack {
+				return err
+			}
+		case "4":
+			if err := s.selectLicenseFile(); err != nil && err != errGoBack {
+				return err
+			}
+		case "5":
+			if !canChooseArch {
+				fmt.Println(s.redText(invalidChoice))
+				break
+			}
+			if err := s.changeArch(); err != nil && err != errGoBack {
+				return err
+			}
+		default:
+			fmt.Println(s.redText(invalidChoice))
+		}
+	}
+}
+
+// changeRelease asks for the release again and updates what depends on it. The products are kept if they're all
+// available for the new release, and the destination follows the release if it was the default one.
+func (s *mpmSession) changeRelease() error {
+	if err := s.selectRelease(); err != nil {
+		return err
+	}
+
+	if s.isDefaultInstallPath(s.installPath) {
+		s.installPath = s.defaultInstallPath()
+	}
+	if !s.recheckProducts() {
+		return s.selectProducts()
+	}
+	return nil
+}
+
+// changeArch asks whether to install the Intel or ARM version again, and gets MPM for it. The release and products
+// are kept if they're available for the new version. Going back undoes the change until MPM has been downloaded for
+// the new version. After that, the old MPM may have been replaced, so the release and products must be settled.
+func (s *mpmSession) changeArch() error {
+	platform, mpmURL, archChoice, mpmDownloadPath := s.platform, s.mpmURL, s.archChoice, s.mpmDownloadPath
+	undo := func() {
+		s.platform, s.mpmURL, s.archChoice, s.mpmDownloadPath = platform, mpmURL, archChoice, mpmDownloadPath
+	}
+
+	err := s.detectPlatform()
+	if err == nil && s.platform != platform {
+		err = s.selectAndDownloadMPM()
+	}
+	if err == errGoBack {
+		undo()
+	}
+	if err != nil || s.platform == platform {
+		return err
+	}
+
+	s.setValidReleases()
+	for {
+		err = nil
+		if _, ok := s.matchRelease(s.release); !ok {
+			fmt.Println(s.redText(s.release + " isn't available for the " + archName(s.platform) + " version. Please choose another release."))
+			err = s.changeRelease()
+		} else if !s.recheckProducts() {
+			err = s.selectProducts()
+		}
+		if err != errGoBack {
+			return err
+		}
+		fmt.Println(s.redText("MPM for the " + archName(s.platform) + " version has already been downloaded, so the release and products need to be chosen for it."))
+	}
+}
+
+// recheckProducts checks the selected products against the current release and platform, adding any newly
+// required products. It returns false, after saying why, if they need to be chosen again.
+func (s *mpmSession) recheckProducts() bool {
+	if len(s.products) == 0 {
+		return false
+	}
+	products, _, unresolved, _ := s.productsFromInput(s.products, catalog.productsFor(s.platform, s.release))
+	if len(unresolved) > 0 {
+		var names []string
+		for _, u := range unresolved {
+			names = append(names, u.input)
+		}
+		fmt.Println(s.redText("These products aren't available for " + s.release + ": " + strings.Join(names, ", ") + ". Please choose your products again."))
+		return false
+	}
+	resolved, err := s.withDependencies(products)
+	if err != nil {
+		fmt.Println(s.redText(err.Error(), ". Please choose your products again."))
+		return false
+	}
+	s.products = resolved
+	return true
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// The name MathWorks uses for each platform in MPM's download URLs. It's what {arch} is replaced with in --mpm-source.
+var mpmArchNames = map[string]string{
+	"windows":  "win64",
+	"linux":    "glnxa64",
+	"macOSx64": "maci64",
+	"macOSARM": "maca64",
+}
+
+// Archives that MPM can be unpacked from, by file extension.
+var mpmArchiveFormats = []string{".zip", ".tar.gz", ".tgz"}
+
+// mpmSourceLocation is where MPM comes from: either a URL to download or a file on this machine.
+type mpmSourceLocation struct {
+	url       string // Set for http and https sources.
+	localPath string // Set for file:// URLs and plain paths.
+}
+
+func (l mpmSourceLocation) String() string {
+	if l.url != "" {
+		return l.url
+	}
+	return l.localPath
+}
+
+// redacted is where MPM comes from, with any password in the URL hidden so that it can be printed.
+func (l mpmSourceLocation) redacted() string {
+	if u, err := url.Parse(l.url); err == nil && l.url != "" {
+		return u.Redacted()
+	}
+	return l.String()
+}
+
+// archiveFormat is the extension of the archive MPM is in, or "" if the source is MPM itself.
+func (l mpmSourceLocation) archiveFormat() string {
+	name := strings.ToLower(l.String())
+	if l.url != "" {
+		if u, err := url.Parse(l.url); err == nil {
+			name = strings.ToLower(u.Path)
+		}
+	}
+	for _, format := range mpmArchiveFormats {
+		if strings.HasSuffix(name, format) {
+			return format
+		}
+	}
+	return ""
+}
+
+// mpmSourceLocation works out where to get MPM from. Without --mpm-source, it's MathWorks' own download URL.
+func (s *mpmSession) mpmSourceLocation() (mpmSourceLocation, error) {
+	if s.mpmSource == "" {
+		return mpmSourceLocation{url: s.mpmURL}, nil
+	}
+	source := strings.ReplaceAll(s.mpmSource, "{arch}", mpmArchNames[s.platform])
+
+	if !strings.Contains(source, "://") {
+		return mpmSourceLocation{localPath: source}, nil
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return mpmSourceLocation{}, fmt.Errorf("invalid MPM source %q: %w", source, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return mpmSourceLocation{url: source}, nil
+	case "file":
+		localPath := u.Path
+		// file:///C:/mpm.exe has a path of /C:/mpm.exe.
+		if len(localPath) >= 3 && localPath[0] == '/' && localPath[2] == ':' {
+			localPath = localPath[1:]
+		}
+		return mpmSourceLocation{localPath: filepath.FromSlash(localPath)}, nil
+	default:
+		return mpmSourceLocation{}, fmt.Errorf("invalid MPM source %q. Use an http, https or file URL, or a path", source)
+	}
+}
+
+// fetchMPM puts MPM at filePath from wherever it's configured to come from, unpacking it if it's in an archive.
+// It returns false if the copy already at filePath was downloaded before and is still current. Either way, the
+// result still needs to go through verifyMPM.
+func (s *mpmSession) fetchMPM(filePath string) (bool, error) {
+	source, err := s.mpmSourceLocation()
+	if err != nil {
+		return false, err
+	}
+	if s.mpmSource != "" {
+		fmt.Println("Getting MPM from " + source.redacted())
+	}
+
+	d := newDownloader(os.Stdout)
+	d.force = s.forceDownload
+	if source.url != "" {
+		if d.client, err = s.network.httpClient(); err != nil {
+			return false, err
+		}
+	}
+
+	format := source.archiveFormat()
+	if format == "" {
+		if source.url != "" {
+			return d.download(source.url, filePath)
+		}
+		return true, copyFile(source.localPath, filePath)
+	}
+
+	// A downloaded archive is kept, so that next time the server can be asked whether it's changed.
+	archivePath := source.localPath
+	if source.url != "" {
+		archivePath = filePath + format
+		updated, err := d.download(source.url, archivePath)
+		if err != nil {
+			return false, err
+		}
+		if _, err := os.Stat(filePath); !updated && err == nil {
+			return false, nil
+		}
+	}
+	return true, extractMPM(archivePath, format, s.mpmBinaryName(), filePath)
+}
+
+// mpmIsTracked reports whether an existing MPM at filePath was downloaded from the configured source and hasn't
+// been touched since, so the server can be asked whether it's current instead of asking the user.
+func (s *mpmSession) mpmIsTracked(filePath string) bool {
+	source, err := s.mpmSourceLocation()
+	if err != nil || source.url == "" || s.forceDownload {
+		return false
+	}
+	if format := source.archiveFormat(); format != "" {
+		return readDownloadRecord(filePath+format).describes(source.url, filePath+format)
+	}
+	return readDownloadRecord(filePath).describes(source.url, filePath)
+}
+
+// copyFile copies a local copy of MPM into place. Like a download, the file only appears once it's complete.
+func copyFile(source, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFileAtomically(destination, in)
+}
+
+func writeFileAtomically(destination string, content io.Reader) error {
+	partPath := destination + ".part"
+	out, err := os.Create(partPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, content); err != nil {
+		out.Close()
+		os.Remove(partPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(partPath)
+		return err
+	}
+	return os.Rename(partPath, destination)
+}
+
+// extractMPM finds binaryName in an archive and writes it to destination. If there's more than one, the one
+// closest to the top of the archive is used.
+func extractMPM(archivePath, format, binaryName, destination string) error {
+	if format == ".zip" {
+		archive, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return fmt.Errorf("error opening %s: %w", archivePath, err)
+		}
+		defer archive.Close()
+
+		var found *zip.File
+		for _, f := range archive.File {
+			if isMPMEntry(f.Name, binaryName, f.FileInfo().IsDir()) && (found == nil || entryDepth(f.Name) < entryDepth(found.Name)) {
+				found = f
+			}
+		}
+		if found == nil {
+			return fmt.Errorf("%s doesn't contain %s", archivePath, binaryName)
+		}
+		content, err := found.Open()
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+		return writeFileAtomically(destination, content)
+	}
+
+	// A tar archive can only be read from start to end, so find the entry first and then read it again.
+	var found string
+	err := walkTarGz(archivePath, func(header *tar.Header, _ io.Reader) error {
+		if isMPMEntry(header.Name, binaryName, header.Typeflag != tar.TypeReg) && (found == "" || entryDepth(header.Name) < entryDepth(found)) {
+			found = header.Name
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if found == "" {
+		return fmt.Errorf("%s doesn't contain %s", archivePath, binaryName)
+	}
+	errFound := errors.New("found")
+	err = walkTarGz(archivePath, func(header *tar.Header, content io.Reader) error {
+		if header.Name != found {
+			return nil
+		}
+		if err := writeFileAtomically(destination, content); err != nil {
+			return err
+		}
+		return errFound
+	})
+	if err == errFound {
+		return nil
+	}
+	return err
+}
+
+func walkTarGz(archivePath string, visit func(header *tar.Header, content io.Reader) error) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", archivePath, err)
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading %s: %w", archivePath, err)
+		}
+		if err := visit(header, archive); err != nil {
+			return err
+		}
+	}
+}
+
+func isMPMEntry(name, binaryName string, isDir bool) bool {
+	return !isDir && path.Base(strings.TrimSuffix(name, "/")) == binaryName
+}
+
+func entryDepth(name string) int {
+	return strings.Count(strings.Trim(name, "/"), "/")
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// savedSession is the part of an mpmSession that's saved after each step, so that --resume can carry on from where
+// an interrupted or failed session stopped. Settings such as the proxy aren't saved; give them again when resuming.
+type savedSession struct {
+	Saved    time.Time `json:"saved"`
+	NextStep string    `json:"nextStep"` // Name of the first step that hasn't finished.
+
+	NonInteractive  bool     `json:"nonInteractive"`
+	Platform        string   `json:"platform"`
+	ArchChoice      string   `json:"archChoice,omitempty"`
+	MPMDownloadPath string   `json:"mpmDownloadPath"`
+	Release         string   `json:"release"`
+	Products        []string `json:"products"`
+	InstallPath     string   `json:"installPath"`
+	LicensePath     string   `json:"licensePath,omitempty"`
+	LicenseUsed     bool     `json:"licenseUsed"`
+}
+
+// Steps that set up the session rather than answer a question. They're run again when resuming.
+var setupSteps = []string{"platform", "options"}
+
+func sessionStatePath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "mpm-go", "session.json"), nil
+}
+
+// saveState records the answers given so far and the step to carry on from. A dry run changes nothing, so it isn't
+// saved. Not being able to save isn't worth stopping an installation for, so errors are ignored.
+func (s *mpmSession) saveState(nextStep string) {
+	if s.dryRun {
+		return
+	}
+	path, err := sessionStatePath()
+	if err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(savedSession{
+		Saved:           time.Now(),
+		NextStep:        nextStep,
+		NonInteractive:  s.nonInteractive,
+		Platform:        s.platform,
+		ArchChoice:      s.archChoice,
+		MPMDownloadPath: s.mpmDownloadPath,
+		Release:         s.release,
+		Products:        s.products,
+		InstallPath:     s.installPath,
+		LicensePath:     s.licensePath,
+		LicenseUsed:     s.licenseUsed,
+	}, "", "    ")
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(path), 0755) == nil {
+		os.WriteFile(path, data, 0600)
+	}
+}
+
+// removeState forgets the saved session once it's finished.
+func (s *mpmSession) removeState() {
+	if path, err := sessionStatePath(); err == nil && !s.dryRun {
+		os.Remove(path)
+	}
+}
+
+// resumeState loads the saved session and works out which step to carry on from. Anything that was done but no
+// longer holds up, such as MPM having been deleted since, is done again.
+func (s *mpmSession) resumeState(steps []wizardStep) (int, error) {
+	path, err := sessionStatePath()
+	if err != nil {
+		return 0, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, errors.New("there's no saved session to resume")
+	} else if err != nil {
+		return 0, err
+	}
+	var saved savedSession
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return 0, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	stepIndex := func(name string) int {
+		return slices.IndexFunc(steps, func(step wizardStep) bool { return step.name == name })
+	}
+	next := stepIndex(saved.NextStep)
+	if next < 0 {
+		return 0, fmt.Errorf("error reading %s: unknown step %q", path, saved.NextStep)
+	}
+	resumeFrom := next
+
+	s.nonInteractive = saved.NonInteractive
+	s.platform = saved.Platform
+	s.archChoice = saved.ArchChoice
+	s.mpmDownloadPath = saved.MPMDownloadPath
+	s.release = saved.Release
+	s.products = saved.Products
+	s.installPath = saved.InstallPath
+	s.licensePath = saved.LicensePath
+	s.licenseUsed = saved.LicenseUsed
+
+	done := func(name string) bool { return stepIndex(name) < next }
+	redo := func(name, problem string) {
+		fmt.Println(s.redText(problem + " That step will be done again."))
+		resumeFrom = min(resumeFrom, stepIndex(name))
+	}
+
+	fmt.Printf("Resuming the session saved %s.\n", saved.Saved.Format(time.DateTime))
+	if done("mpm") {
+		mpmPath := filepath.Join(s.mpmDownloadPath, s.mpmBinaryName())
+		fmt.Println("   MPM:          " + mpmPath)
+		if platforms, _, err := executablePlatforms(mpmPath); err != nil || !slices.Contains(platforms, s.platform) {
+			redo("mpm", "MPM is missing from "+mpmPath+" or has changed.")
+		}
+	}
+	if done("release") {
+		fmt.Println("   Release:      " + s.release)
+	}
+	if done("products") {
+		fmt.Printf("   Products:     %d\n", len(s.products))
+	}
+	if done("destination") {
+		fmt.Println("   Destination:  " + s.installPath)
+		// Default paths are left for MPM to create, so only other ones are expected to exist.
+		if _, err := os.Stat(s.installPath); err != nil && !s.isDefaultInstallPath(s.installPath) {
+			redo("destination", s.installPath+" no longer exists.")
+		}
+	}
+	if done("license") {
+		if !s.licenseUsed {
+			fmt.Println("   License file: none")
+		} else {
+			fmt.Println("   License file: " + s.licensePath)
+			if err := checkLicenseFile(s.licensePath); err != nil {
+				redo("license", "The license file can't be used any more: "+err.Error()+".")
+			}
+		}
+	}
+	if resumeFrom < len(steps) {
+		fmt.Printf("Continuing from step %d of %d.\n\n", resumeFrom+1, len(steps))
+	}
+	return resumeFrom, nil
+}
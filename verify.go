@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// expectedMPMChecksum is the SHA-256 MPM must have, or "" if none was given. A checksum given directly wins over one
+// from the manifest, which is a JSON object of platform names to checksums, such as {"linux": "9f86d0..."}.
+func (s *mpmSession) expectedMPMChecksum() (string, error) {
+	if s.mpmChecksum != "" {
+		if !sha256Pattern.MatchString(s.mpmChecksum) {
+			return "", fmt.Errorf("%q is not a SHA-256 checksum", s.mpmChecksum)
+		}
+		return strings.ToLower(s.mpmChecksum), nil
+	}
+	if s.mpmManifestPath == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(s.mpmManifestPath)
+	if err != nil {
+		return "", fmt.Errorf("error reading the MPM manifest: %w", err)
+	}
+	var checksums map[string]string
+	if err := json.Unmarshal(data, &checksums); err != nil {
+		return "", fmt.Errorf("error reading %s: %w", s.mpmManifestPath, err)
+	}
+	checksum, ok := checksums[s.platform]
+	if !ok {
+		return "", fmt.Errorf("%s has no checksum for %s", s.mpmManifestPath, s.platform)
+	}
+	if !sha256Pattern.MatchString(checksum) {
+		return "", fmt.Errorf("%s: %q is not a SHA-256 checksum", s.mpmManifestPath, checksum)
+	}
+	return strings.ToLower(checksum), nil
+}
+
+// verifyMPM checks that a freshly downloaded MPM is an executable for the selected platform and, if a checksum was
+// given, that it's the exact file expected. A file that fails is deleted so that it's never run.
+func (s *mpmSession) verifyMPM(path string) error {
+	err := s.checkMPM(path)
+	if err != nil {
+		if removeErr := os.Remove(path); removeErr != nil {
+			err = errors.Join(err, fmt.Errorf("the file could not be deleted: %w", removeErr))
+		}
+		return fmt.Errorf("the downloaded MPM failed verification: %w", err)
+	}
+	return nil
+}
+
+func (s *mpmSession) checkMPM(path string) error {
+	expected, err := s.expectedMPMChecksum()
+	if err != nil {
+		return err
+	}
+	if expected != "" {
+		actual, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			return fmt.Errorf("its SHA-256 is %s, but %s was expected", actual, expected)
+		}
+	}
+
+	platforms, description, err := executablePlatforms(path)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(platforms, s.platform) {
+		return fmt.Errorf("it is %s, not MPM for %s", description, s.platform)
+	}
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// executablePlatforms works out which platforms an executable runs on from its headers, along with a description of
+// it for error messages. Universal Mach-O files run on both macOS platforms.
+func executablePlatforms(path string) (platforms []string, description string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return nil, "", errors.New("it is too small to be an executable")
+	}
+
+	switch {
+	case bytes.Equal(magic, []byte(elf.ELFMAG)):
+		f, err := elf.NewFile(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("it is a damaged ELF file: %w", err)
+		}
+		if f.Type != elf.ET_EXEC && f.Type != elf.ET_DYN {
+			return nil, fmt.Sprintf("an ELF %s file", f.Type), nil
+		}
+		if f.Machine == elf.EM_X86_64 {
+			platforms = []string{"linux"}
+		}
+		return platforms, "a Linux executable for " + cpuName(f.Machine.String()), nil
+
+	case bytes.Equal(magic[:2], []byte("MZ")):
+		f, err := pe.NewFile(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("it is a damaged Windows executable: %w", err)
+		}
+		if f.Machine == pe.IMAGE_FILE_MACHINE_AMD64 {
+			platforms = []string{"windows"}
+		}
+		return platforms, "a Windows executable for " + cpuName(fmt.Sprintf("%#x", f.Machine)), nil
+	}
+
+	if f, err := macho.NewFatFile(file); err == nil {
+		var cpus []string
+		for _, arch := range f.Arches {
+			cpus = append(cpus, cpuName(arch.Cpu.String()))
+			if platform := machoPlatform(arch.Cpu); platform != "" && !slices.Contains(platforms, platform) {
+				platforms = append(platforms, platform)
+			}
+		}
+		return platforms, "a universal macOS executable for " + strings.Join(cpus, " and "), nil
+	}
+	if f, err := macho.NewFile(file); err == nil {
+		if f.Type != macho.TypeExec {
+			return nil, "a Mach-O file that isn't an executable", nil
+		}
+		if platform := machoPlatform(f.Cpu); platform != "" {
+			platforms = []string{platform}
+		}
+		return platforms, "a macOS executable for " + cpuName(f.Cpu.String()), nil
+	}
+
+	return nil, "", errors.New("it is not an executable. The server may have sent an error page instead")
+}
+
+// cpuName turns the machine types used by debug/elf, debug/pe and debug/macho into the names people know them by.
+func cpuName(machine string) string {
+	switch machine {
+	case "EM_X86_64", "0x8664", "CpuAmd64":
+		return "x86-64"
+	case "EM_AARCH64", "0xaa64", "CpuArm64":
+		return "arm64"
+	case "EM_386", "0x14c", "Cpu386":
+		return "32-bit x86"
+	}
+	return machine
+}
+
+func machoPlatform(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		return "macOSx64"
+	case macho.CpuArm64:
+		return "macOSARM"
+	}
+	return ""
+}